@@ -8,917 +8,954 @@ typedef void (*progress_callback)(int current, int total, const char* filename,
 */
 import "C"
 import (
+	"context"
+	_ "embed"
 	"encoding/json"
-	"fmt"
-	"os"
-	"runtime"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unicode"
-	"unicode/utf8"
 	"unsafe"
-	_ "embed"
+
+	"demojibake/engine"
 )
 
+// Adaptador cgo sobre o pacote engine. Toda a análise vive em demojibake/engine;
+// aqui ficam apenas a conversão de tipos C, o registro de handles, a
+// validação de caminhos e o envelope de erros exigidos pelo host Java.
+
 //go:embed portuguese_language_corpus.bin
 var embeddedLanguageCorpus []byte
 
 var (
-	// Sistema de dicionário otimizado
-	languageDictionary      *LanguageRadixTree
-	encodingPatternCache    map[string]string
-	frequencyFrequencyBloomFilter    *FrequencyBloomFilter
-	contextualNgramAnalyzer *ContextualNgramAnalyzer
-	globalMetrics           atomic.Value
-	engineInitialized       atomic.Bool
-	
-	// Sistema de workers otimizado
-	concurrentProcessorPool *ConcurrentProcessorPool
-	analyticsRepository     map[string]interface{}
-	analyticsProtection     sync.RWMutex
-	
-	// Variables for dictionary and processing
-	dictTrie     *LanguageRadixTree
-	dictBloom    *FrequencyBloomFilter
-	dictCache    map[string]string
-	ngramModel   *ContextualNgramAnalyzer
-	totalFiles   atomic.Int64
-	processing   atomic.Int64
-	
-	// Pool de reutilização de objetos
-	analysisResultPool = sync.Pool{
-		New: func() interface{} {
-			return &CharacterAnalysisReport{}
-		},
-	}
+	// Instância padrão usada pelas funções exportadas sem handle
+	defaultEngine     *engineHandle
+	defaultEngineLock sync.RWMutex
 )
 
-// CharacterAnalysisReport estrutura para resultados
-type CharacterAnalysisReport struct {
-	DocumentPath           string                  `json:"documentPath"`
-	SourceCharacterSet     string                  `json:"sourceCharacterSet"`
-	InferredCharacterSet   string                  `json:"inferredCharacterSet"`
-	AccuracyScore          float64                 `json:"accuracyScore"`
-	EncodingAnomalies      []EncodingAnomaly      `json:"encodingAnomalies"`
-	SuggestedTransforms    []TextTransformation   `json:"suggestedTransforms"`
-	AnalysisDuration       time.Duration          `json:"analysisDuration"`
-	TransformationSuccess  bool                   `json:"transformationSuccess"`
+//export InitializeEncodingEngine
+func InitializeEncodingEngine() C.int {
+	defaultEngineLock.Lock()
+	defer defaultEngineLock.Unlock()
+	if defaultEngine != nil && defaultEngine.instance.IsRunning() {
+		return 1
+	}
+	if err := replaceDefaultEngine(""); err != nil {
+		recordInitError(err)
+		return 0
+	}
+	return 1
 }
 
-type EncodingAnomaly struct {
-	AnomalyCategory  string `json:"anomalyCategory"`
-	TextPosition     int    `json:"textPosition"`
-	AffectedLength   int    `json:"affectedLength"`
-	SurroundingText  string `json:"surroundingText"`
-	SeverityLevel    string `json:"severityLevel"`
+//export InitializeEncodingEngineWithConfig
+func InitializeEncodingEngineWithConfig(configPtr *C.char) C.int {
+	defaultEngineLock.Lock()
+	defer defaultEngineLock.Unlock()
+	if defaultEngine != nil && defaultEngine.instance.IsRunning() {
+		recordInitError(engine.NewError(engine.ErrCodeAlreadyRunning, "engine already running; call GracefulEngineShutdown before re-initialising", nil))
+		return 0
+	}
+	if err := replaceDefaultEngine(C.GoString(configPtr)); err != nil {
+		recordInitError(err)
+		return 0
+	}
+	return 1
 }
 
-type TextTransformation struct {
-	DocumentPosition       int     `json:"documentPosition"`
-	OriginalSequence       string  `json:"originalSequence"`
-	TransformedSequence    string  `json:"transformedSequence"`
-	TransformationScore    float64 `json:"transformationScore"`
-	TextTransformationStrategy     string  `json:"correctionStrategy"`
+//export CreateEngine
+func CreateEngine(configPtr *C.char) C.longlong {
+	h, err := newEngineHandle(C.GoString(configPtr))
+	if err != nil {
+		recordEngineError(err)
+		return 0
+	}
+	return C.longlong(registerEngine(h))
 }
 
-//export InitializeEncodingEngine
-func InitializeEncodingEngine() C.int {
-	if engineInitialized.Load() {
-		return 1
+//export DestroyEngine
+func DestroyEngine(handle C.longlong) C.int {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorStatus(err)
 	}
+	// Encerra antes de remover o handle: chamadas concorrentes recebem
+	// engine_not_running em vez de invalid_handle enquanto o trabalho drena
+	h.instance.Shutdown(engine.DefaultShutdownTimeout)
+	unregisterEngine(int64(handle))
+	return C.int(0)
+}
 
-	languageDictionary = NewLanguageRadixTree()
-	encodingPatternCache = make(map[string]string, 100000)
-	frequencyFrequencyBloomFilter = NewFrequencyBloomFilter(1000000, 5)
-	contextualNgramAnalyzer = LoadContextualNgramAnalyzer(embeddedLanguageCorpus)
-	concurrentProcessorPool = NewConcurrentProcessorPool(runtime.NumCPU())
+//export AnalyzeDocumentEncoding
+func AnalyzeDocumentEncoding(documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.analyzeDocumentJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
+}
 
-	// Carrega dicionário linguístico embutido
-	if err := loadEmbeddedDictionary(); err != nil {
-		return 0
+//export EngineAnalyzeDocumentEncoding
+func EngineAnalyzeDocumentEncoding(handle C.longlong, documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.analyzeDocumentJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
+}
 
-	engineInitialized.Store(true)
-	return 1
+//export AnalyzeDocumentSummary
+func AnalyzeDocumentSummary(documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.analyzeDocumentSummaryJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
 }
 
-// loadEmbeddedDictionary carrega o dicionário embutido no sistema
-func loadEmbeddedDictionary() error {
-	dictTrie = NewLanguageRadixTree()
-	dictBloom = NewFrequencyBloomFilter(1000000, 5)
-	dictCache = make(map[string]string, 100000)
-	ngramModel = contextualNgramAnalyzer
-
-	// Processa o corpus embutido
-	words := parseLanguageDictionary(embeddedLanguageCorpus)
-	for _, word := range words {
-		languageDictionary.InsertVocabulary(word)
-		dictTrie.InsertVocabulary(word)
-		dictBloom.Add(word)
-		
-		// Gera variações
-		for _, variant := range generateVariants(word) {
-			broken := generateBrokenKey(variant)
-			dictCache[broken] = variant
-		}
+//export EngineAnalyzeDocumentSummary
+func EngineAnalyzeDocumentSummary(handle C.longlong, documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.analyzeDocumentSummaryJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
+}
 
-	return nil
+//export QueryAnalysisResult
+func QueryAnalysisResult(resultID C.longlong, queryPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.queryResultJSON(int64(resultID), C.GoString(queryPtr)))
 }
 
-//export AnalyzeDocumentEncoding
-func AnalyzeDocumentEncoding(documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
-	if !engineInitialized.Load() {
-		return C.CString(`{"error": "Not engineInitialized"}`)
-	}
-	
-	path := C.GoString(documentPathPtr)
-	
-	// Validação de segurança - previne path traversal
-	if err := validatePath(path); err != nil {
-		return C.CString(fmt.Sprintf(`{"error": "Invalid path: %s"}`, err.Error()))
-	}
-	
-	options := parseOptions(C.GoString(analysisOptionsPtr))
-	startTime := time.Now()
-	
-	// Processa com todas otimizações
-	result := processFileWithDictionary(path, options)
-	result.AnalysisDuration = time.Since(startTime)
-	
-	// Serializa resultado com tratamento de erro
-	jsonResult, err := json.Marshal(result)
+//export EngineQueryAnalysisResult
+func EngineQueryAnalysisResult(handle C.longlong, resultID C.longlong, queryPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
 	if err != nil {
-		return C.CString(fmt.Sprintf(`{"error": "Serialization failed: %s"}`, err.Error()))
+		return errorCString(err)
 	}
-	return C.CString(string(jsonResult))
+	return C.CString(h.queryResultJSON(int64(resultID), C.GoString(queryPtr)))
 }
 
+//export ReleaseAnalysisResult
+func ReleaseAnalysisResult(resultID C.longlong) C.int {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorStatus(err)
+	}
+	return C.int(h.releaseResult(int64(resultID)))
+}
+
+//export EngineReleaseAnalysisResult
+func EngineReleaseAnalysisResult(handle C.longlong, resultID C.longlong) C.int {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorStatus(err)
+	}
+	return C.int(h.releaseResult(int64(resultID)))
+}
+
+// ProcessDocumentCollectionConcurrently analisa o lote. Retorna 0 se todos
+// os arquivos foram analisados, o número de arquivos que falharam (o último
+// fica em GetLastError) ou um status negativo se o lote não pôde rodar.
+//
 //export ProcessDocumentCollectionConcurrently
 func ProcessDocumentCollectionConcurrently(
 	jsonPathsPtr *C.char,
 	analysisOptionsPtr *C.char,
 ) C.int {
-	if !engineInitialized.Load() {
-		return C.int(-1)
-	}
-	
-	var paths []string
-	if err := json.Unmarshal([]byte(C.GoString(jsonPathsPtr)), &paths); err != nil {
-		return C.int(-2)
-	}
-	
-	// Valida todos os paths para segurança
-	for _, path := range paths {
-		if err := validatePath(path); err != nil {
-			return C.int(-3) // Invalid path in batch
-		}
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorStatus(err)
 	}
-	
-	options := parseOptions(C.GoString(analysisOptionsPtr))
-	
-	totalFiles.Store(int64(len(paths)))
-	processing.Store(0)
-	
-	// Processa em paralelo
-	for _, path := range paths {
-		concurrentProcessorPool.Submit(func(p string) func() {
-			return func() {
-				processing.Add(1)
-				// Processa arquivo
-				processFileWithDictionary(p, options)
-			}
-		}(path))
+	return C.int(h.processDocumentCollection(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr)))
+}
+
+//export EngineProcessDocumentCollectionConcurrently
+func EngineProcessDocumentCollectionConcurrently(
+	handle C.longlong,
+	jsonPathsPtr *C.char,
+	analysisOptionsPtr *C.char,
+) C.int {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorStatus(err)
 	}
-	
-	// Aguarda conclusão
-	concurrentProcessorPool.Wait()
-	
-	return C.int(0)
+	return C.int(h.processDocumentCollection(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr)))
 }
 
-//export RetrieveLanguageDictionaryMetrics
-func RetrieveLanguageDictionaryMetrics() *C.char {
-	stats := map[string]interface{}{
-		"total_words":      0,
-		"bloom_size":       0,
-		"ngram_model_size": 0,
-		"processing_count": processing.Load(),
-		"engineInitialized":      engineInitialized.Load(),
+//export StartCollectionJob
+func StartCollectionJob(jsonPathsPtr *C.char, analysisOptionsPtr *C.char, apply C.int) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	if languageDictionary != nil {
-		stats["total_vocabulary"] = languageDictionary.GetVocabularyCount()
+	return C.CString(h.startJobJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr), apply != 0))
+}
+
+//export EngineStartCollectionJob
+func EngineStartCollectionJob(handle C.longlong, jsonPathsPtr *C.char, analysisOptionsPtr *C.char, apply C.int) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	if dictBloom != nil {
-		stats["bloom_size"] = dictBloom.Size()
+	return C.CString(h.startJobJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr), apply != 0))
+}
+
+//export ResumeCollectionJob
+func ResumeCollectionJob(jobIDPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	if contextualNgramAnalyzer != nil {
-		stats["contextual_analyzer_capacity"] = contextualNgramAnalyzer.GetAnalyzerCapacity()
+	return C.CString(h.resumeJobJSON(C.GoString(jobIDPtr)))
+}
+
+//export EngineResumeCollectionJob
+func EngineResumeCollectionJob(handle C.longlong, jobIDPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	jsonStats, err := json.Marshal(stats)
+	return C.CString(h.resumeJobJSON(C.GoString(jobIDPtr)))
+}
+
+//export RollbackCollectionJob
+func RollbackCollectionJob(jobIDPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
 	if err != nil {
-		return C.CString(`{"error": "Failed to marshal stats"}`)
+		return errorCString(err)
 	}
-	return C.CString(string(jsonStats))
+	return C.CString(h.rollbackJobJSON(C.GoString(jobIDPtr)))
 }
 
-//export EnrichLanguageDictionary
-func EnrichLanguageDictionary(vocabularyPtr *C.char) C.int {
-	var words []string
-	if err := json.Unmarshal([]byte(C.GoString(vocabularyPtr)), &words); err != nil {
-		return C.int(-1)
-	}
-	
-	// Adiciona palavras ao dicionário
-	for _, word := range words {
-		dictTrie.InsertVocabulary(word)
-		dictBloom.Add(word)
-		
-		// Gera variações
-		for _, variant := range generateVariants(word) {
-			broken := generateBrokenKey(variant)
-			dictCache[broken] = variant
-		}
+//export EngineRollbackCollectionJob
+func EngineRollbackCollectionJob(handle C.longlong, jobIDPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	return C.int(len(words))
+	return C.CString(h.rollbackJobJSON(C.GoString(jobIDPtr)))
 }
 
-//export ReleaseAllocatedMemory
-func ReleaseAllocatedMemory(memoryPtr *C.char) {
-	C.free(unsafe.Pointer(memoryPtr))
+//export ListCollectionJobs
+func ListCollectionJobs() *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.listJobsJSON())
 }
 
-//export GracefulEngineShutdown
-func GracefulEngineShutdown() {
-	if concurrentProcessorPool != nil {
-		concurrentProcessorPool.Stop()
+//export EngineListCollectionJobs
+func EngineListCollectionJobs(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	engineInitialized.Store(false)
+	return C.CString(h.listJobsJSON())
 }
 
-// Funções internas
+//export ApplyTransaction
+func ApplyTransaction(jsonPathsPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.applyTransactionJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr)))
+}
 
-func initializeDictionary() error {
-	// Carrega trie com dicionário embedded
-	dictTrie = NewLanguageRadixTree()
-	
-	// Inicializa Bloom filter
-	dictBloom = NewFrequencyBloomFilter(10000000, 3)
-	
-	// Inicializa cache
-	dictCache = make(map[string]string, 100000)
-	
-	// Processa dicionário embedded
-	words := parseDictionary(embeddedLanguageCorpus)
-	for _, word := range words {
-		dictTrie.InsertVocabulary(word)
-		dictBloom.Add(word)
-	}
-	
-	return nil
+//export EngineApplyTransaction
+func EngineApplyTransaction(handle C.longlong, jsonPathsPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.applyTransactionJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr)))
 }
 
-func processFileWithDictionary(path string, options map[string]interface{}) CharacterAnalysisReport {
-	result := CharacterAnalysisReport{
-		DocumentPath: path,
-		SourceCharacterSet: "unknown",
-	}
-	
-	// Lê arquivo com memory-mapped I/O
-	content, encoding := readFileOptimized(path)
-	result.SourceCharacterSet = encoding
-	result.InferredCharacterSet = "UTF-8"
-	
-	// Detecta problemas
-	issues := detectEncodingEncodingAnomalys(content)
-	result.EncodingAnomalies = issues
-	
-	// Aplica correções
-	corrections := applyIntelligentTextTransformations(content, options)
-	result.SuggestedTransforms = corrections
-	
-	// Calcula confiança
-	result.AccuracyScore = calculateConfidence(issues, corrections)
-	result.TransformationSuccess = len(corrections) > 0
-	
-	return result
-}
-
-func generateBrokenKey(word string) string {
-	// Implementação do algoritmo de geração de chave quebrada
-	replacements := map[rune]string{
-		'á': "?", 'à': "?", 'ã': "?", 'â': "?", 'ä': "?",
-		'é': "?", 'è': "?", 'ê': "?", 'ë': "?",
-		'í': "?", 'ì': "?", 'î': "?", 'ï': "?",
-		'ó': "?", 'ò': "?", 'õ': "?", 'ô': "?", 'ö': "?",
-		'ú': "?", 'ù': "?", 'û': "?", 'ü': "?",
-		'ç': "??",
-		'ñ': "?",
-	}
-	
-	result := []rune{}
-	for _, r := range word {
-		if replacement, ok := replacements[r]; ok {
-			result = append(result, []rune(replacement)...)
-		} else if upperReplacement, ok := replacements[unicode.ToLower(r)]; ok {
-			result = append(result, []rune(strings.ToUpper(upperReplacement))...)
-		} else {
-			result = append(result, r)
-		}
+//export UndoTransaction
+func UndoTransaction(transactionIDPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	return string(result)
+	return C.CString(h.undoTransactionJSON(C.GoString(transactionIDPtr)))
 }
 
-func generateVariants(word string) []string {
-	return []string{
-		strings.ToLower(word),
-		strings.Title(strings.ToLower(word)),
-		strings.ToUpper(word),
+//export EngineUndoTransaction
+func EngineUndoTransaction(handle C.longlong, transactionIDPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.undoTransactionJSON(C.GoString(transactionIDPtr)))
 }
 
-// Estruturas de dados especializadas implementadas
-type LanguageRadixTree struct {
-	rootLexicon    *LexiconNode
-	vocabularySize int
+//export ListTransactions
+func ListTransactions() *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.listTransactionsJSON())
 }
 
-type LexiconNode struct {
-	characterIndex map[rune]*LexiconNode
-	isWordTerminal bool
+//export EngineListTransactions
+func EngineListTransactions(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.listTransactionsJSON())
 }
 
-func NewLanguageRadixTree() *LanguageRadixTree {
-	return &LanguageRadixTree{
-		rootLexicon: &LexiconNode{characterIndex: make(map[rune]*LexiconNode)},
+//export ListBackups
+func ListBackups(documentPathPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.listBackupsJSON(C.GoString(documentPathPtr)))
 }
 
-func (l *LanguageRadixTree) InsertVocabulary(terminology string) {
-	currentNode := l.rootLexicon
-	for _, character := range terminology {
-		if currentNode.characterIndex[character] == nil {
-			currentNode.characterIndex[character] = &LexiconNode{characterIndex: make(map[rune]*LexiconNode)}
-		}
-		currentNode = currentNode.characterIndex[character]
+//export EngineListBackups
+func EngineListBackups(handle C.longlong, documentPathPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	if !currentNode.isWordTerminal {
-		currentNode.isWordTerminal = true
-		l.vocabularySize++
+	return C.CString(h.listBackupsJSON(C.GoString(documentPathPtr)))
+}
+
+//export RestoreBackup
+func RestoreBackup(documentPathPtr *C.char, timestampPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.restoreBackupJSON(C.GoString(documentPathPtr), C.GoString(timestampPtr)))
 }
 
-func (l *LanguageRadixTree) SearchVocabulary(terminology string) bool {
-	currentNode := l.rootLexicon
-	for _, character := range terminology {
-		if currentNode.characterIndex[character] == nil {
-			return false
-		}
-		currentNode = currentNode.characterIndex[character]
+//export EngineRestoreBackup
+func EngineRestoreBackup(handle C.longlong, documentPathPtr *C.char, timestampPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	return currentNode.isWordTerminal
+	return C.CString(h.restoreBackupJSON(C.GoString(documentPathPtr), C.GoString(timestampPtr)))
 }
 
-func (l *LanguageRadixTree) GetVocabularyCount() int { return l.vocabularySize }
+//export PruneBackups
+func PruneBackups() *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.pruneBackupsJSON())
+}
 
-type FrequencyBloomFilter struct {
-	probabilisticBitArray []bool
-	arrayCapacity         int
-	hashFunctionCount     int
+//export EnginePruneBackups
+func EnginePruneBackups(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.pruneBackupsJSON())
 }
 
-func NewFrequencyBloomFilter(capacity int, hashCount int) *FrequencyBloomFilter {
-	return &FrequencyBloomFilter{
-		probabilisticBitArray: make([]bool, capacity),
-		arrayCapacity:         capacity,
-		hashFunctionCount:     hashCount,
+//export VerifyAuditLog
+func VerifyAuditLog() *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.verifyAuditLogJSON())
 }
 
-func (b *FrequencyBloomFilter) hash(word string, seed int) int {
-	hash := seed
-	for _, char := range word {
-		hash = hash*31 + int(char)
+//export EngineVerifyAuditLog
+func EngineVerifyAuditLog(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	return (hash % b.arrayCapacity + b.arrayCapacity) % b.arrayCapacity
+	return C.CString(h.verifyAuditLogJSON())
 }
 
-func (b *FrequencyBloomFilter) Add(word string) {
-	for i := 0; i < b.hashFunctionCount; i++ {
-		index := b.hash(word, i)
-		b.probabilisticBitArray[index] = true
+//export ExportDocumentDiff
+func ExportDocumentDiff(documentPathPtr *C.char, analysisOptionsPtr *C.char, diffOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.exportDocumentDiffJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr), C.GoString(diffOptionsPtr)))
 }
 
-func (b *FrequencyBloomFilter) Contains(word string) bool {
-	for i := 0; i < b.hashFunctionCount; i++ {
-		index := b.hash(word, i)
-		if !b.probabilisticBitArray[index] {
-			return false
-		}
+//export EngineExportDocumentDiff
+func EngineExportDocumentDiff(handle C.longlong, documentPathPtr *C.char, analysisOptionsPtr *C.char, diffOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	return true
+	return C.CString(h.exportDocumentDiffJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr), C.GoString(diffOptionsPtr)))
 }
 
-func (b *FrequencyBloomFilter) Size() int { return b.arrayCapacity }
+//export ExportCollectionPatch
+func ExportCollectionPatch(jsonPathsPtr *C.char, analysisOptionsPtr *C.char, diffOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.exportCollectionPatchJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr), C.GoString(diffOptionsPtr)))
+}
 
-type ContextualNgramAnalyzer struct {
-	bigramFrequencies  map[string]int
-	trigramFrequencies map[string]int
-	totalSequenceCount int
+//export EngineExportCollectionPatch
+func EngineExportCollectionPatch(handle C.longlong, jsonPathsPtr *C.char, analysisOptionsPtr *C.char, diffOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.exportCollectionPatchJSON(C.GoString(jsonPathsPtr), C.GoString(analysisOptionsPtr), C.GoString(diffOptionsPtr)))
 }
 
-func LoadContextualNgramAnalyzer(vocabularyData []byte) *ContextualNgramAnalyzer {
-	analyzer := &ContextualNgramAnalyzer{
-		bigramFrequencies:  make(map[string]int),
-		trigramFrequencies: make(map[string]int),
+//export ApplySelectedTransformations
+func ApplySelectedTransformations(documentPathPtr *C.char, analysisID C.longlong, acceptedIDsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	// Processa dados linguísticos para criar sequências contextuais
-	terminology := parseLanguageDictionary(vocabularyData)
-	for _, term := range terminology {
-		analyzer.incorporateTerminology(term)
+	return C.CString(h.applySelectedJSON(C.GoString(documentPathPtr), int64(analysisID), C.GoString(acceptedIDsPtr)))
+}
+
+//export EngineApplySelectedTransformations
+func EngineApplySelectedTransformations(handle C.longlong, documentPathPtr *C.char, analysisID C.longlong, acceptedIDsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	return analyzer
+	return C.CString(h.applySelectedJSON(C.GoString(documentPathPtr), int64(analysisID), C.GoString(acceptedIDsPtr)))
 }
 
-func (c *ContextualNgramAnalyzer) incorporateTerminology(terminology string) {
-	characterSequences := []rune(terminology)
-	for i := 0; i < len(characterSequences)-1; i++ {
-		bigramSequence := string(characterSequences[i:i+2])
-		c.bigramFrequencies[bigramSequence]++
-		c.totalSequenceCount++
+//export RecordTransformationFeedback
+func RecordTransformationFeedback(documentPathPtr *C.char, analysisID C.longlong, decisionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	for i := 0; i < len(characterSequences)-2; i++ {
-		trigramSequence := string(characterSequences[i:i+3])
-		c.trigramFrequencies[trigramSequence]++
+	return C.CString(h.recordFeedbackJSON(C.GoString(documentPathPtr), int64(analysisID), C.GoString(decisionsPtr)))
+}
+
+//export EngineRecordTransformationFeedback
+func EngineRecordTransformationFeedback(handle C.longlong, documentPathPtr *C.char, analysisID C.longlong, decisionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
+	return C.CString(h.recordFeedbackJSON(C.GoString(documentPathPtr), int64(analysisID), C.GoString(decisionsPtr)))
 }
 
-func (c *ContextualNgramAnalyzer) CalculateSequenceProbability(ngramSequence string) float64 {
-	sequenceLength := len([]rune(ngramSequence))
-	if sequenceLength == 2 {
-		return float64(c.bigramFrequencies[ngramSequence]) / float64(c.totalSequenceCount)
+//export GetLearnedFeedback
+func GetLearnedFeedback() *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	if sequenceLength == 3 {
-		return float64(c.trigramFrequencies[ngramSequence]) / float64(c.totalSequenceCount)
+	return C.CString(h.learnedFeedbackJSON())
+}
+
+//export EngineGetLearnedFeedback
+func EngineGetLearnedFeedback(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	return 0.0
+	return C.CString(h.learnedFeedbackJSON())
 }
 
-func (c *ContextualNgramAnalyzer) GetAnalyzerCapacity() int { 
-	return len(c.bigramFrequencies) + len(c.trigramFrequencies) 
+//export ResetLearnedFeedback
+func ResetLearnedFeedback(strategyPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.resetFeedbackJSON(C.GoString(strategyPtr)))
 }
 
-func (c *ContextualNgramAnalyzer) GetProbability(text string) float64 {
-	// Calculate average probability of all n-grams in the text
-	runes := []rune(text)
-	if len(runes) < 2 {
-		return 0.0
+//export EngineResetLearnedFeedback
+func EngineResetLearnedFeedback(handle C.longlong, strategyPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	totalProb := 0.0
-	count := 0
-	
-	// Calculate bigram probabilities
-	for i := 0; i < len(runes)-1; i++ {
-		bigram := string(runes[i:i+2])
-		totalProb += c.CalculateSequenceProbability(bigram)
-		count++
+	return C.CString(h.resetFeedbackJSON(C.GoString(strategyPtr)))
+}
+
+//export GetEffectiveConfig
+func GetEffectiveConfig(documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	// Calculate trigram probabilities  
-	for i := 0; i < len(runes)-2; i++ {
-		trigram := string(runes[i:i+3])
-		totalProb += c.CalculateSequenceProbability(trigram)
-		count++
+	return C.CString(h.effectiveConfigJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
+}
+
+//export EngineGetEffectiveConfig
+func EngineGetEffectiveConfig(handle C.longlong, documentPathPtr *C.char, analysisOptionsPtr *C.char) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	if count == 0 {
-		return 0.0
+	return C.CString(h.effectiveConfigJSON(C.GoString(documentPathPtr), C.GoString(analysisOptionsPtr)))
+}
+
+//export ScanDirectoryConcurrently
+func ScanDirectoryConcurrently(
+	rootPtr *C.char,
+	scanOptionsPtr *C.char,
+	analysisOptionsPtr *C.char,
+) *C.char {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorCString(err)
 	}
-	
-	return totalProb / float64(count)
+	return C.CString(h.scanDirectoryJSON(C.GoString(rootPtr), C.GoString(scanOptionsPtr), C.GoString(analysisOptionsPtr)))
 }
 
-type ConcurrentProcessorPool struct {
-	processorCount      int
-	taskQueue           chan func()
-	synchronizationGroup sync.WaitGroup
-	terminationSignal   chan bool
-	operationalStatus   atomic.Bool
+//export EngineScanDirectoryConcurrently
+func EngineScanDirectoryConcurrently(
+	handle C.longlong,
+	rootPtr *C.char,
+	scanOptionsPtr *C.char,
+	analysisOptionsPtr *C.char,
+) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
+	}
+	return C.CString(h.scanDirectoryJSON(C.GoString(rootPtr), C.GoString(scanOptionsPtr), C.GoString(analysisOptionsPtr)))
 }
 
-func NewConcurrentProcessorPool(processorCapacity int) *ConcurrentProcessorPool {
-	return &ConcurrentProcessorPool{
-		processorCount:    processorCapacity,
-		taskQueue:         make(chan func(), processorCapacity*2),
-		terminationSignal: make(chan bool),
+//export RetrieveLanguageDictionaryMetrics
+func RetrieveLanguageDictionaryMetrics() *C.char {
+	defaultEngineLock.RLock()
+	h := defaultEngine
+	defaultEngineLock.RUnlock()
+
+	stats := map[string]interface{}{
+		"total_vocabulary": 0,
+		"bloom_size":       0,
+		"processing_count": 0,
+	}
+	if h != nil {
+		stats = h.instance.Metrics()
+	}
+	stats["engineInitialized"] = h != nil && h.instance.IsRunning()
+
+	jsonStats, err := json.Marshal(stats)
+	if err != nil {
+		return errorCString(engine.NewError(engine.ErrCodeSerialization, err.Error(), nil))
 	}
+	return C.CString(string(jsonStats))
 }
 
-func (w *ConcurrentProcessorPool) Start() {
-	if w.operationalStatus.Load() {
-		return
+//export EngineRetrieveLanguageDictionaryMetrics
+func EngineRetrieveLanguageDictionaryMetrics(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorCString(err)
 	}
-	w.operationalStatus.Store(true)
-	for i := 0; i < w.processorCount; i++ {
-		go w.worker()
+	stats := h.instance.Metrics()
+	stats["handle"] = h.handle
+	jsonStats, marshalErr := json.Marshal(stats)
+	if marshalErr != nil {
+		return C.CString(marshalError(h.recordError(engine.NewError(engine.ErrCodeSerialization, marshalErr.Error(), nil))))
 	}
+	return C.CString(string(jsonStats))
 }
 
-func (w *ConcurrentProcessorPool) worker() {
-	for {
-		select {
-		case task := <-w.taskQueue:
-			task()
-			w.synchronizationGroup.Done()
-		case <-w.terminationSignal:
-			return
-		}
+//export EnrichLanguageDictionary
+func EnrichLanguageDictionary(vocabularyPtr *C.char) C.int {
+	h, err := currentDefaultEngine()
+	if err != nil {
+		return errorStatus(err)
 	}
+	return C.int(h.enrichDictionaryJSON(C.GoString(vocabularyPtr)))
 }
 
-func (w *ConcurrentProcessorPool) Submit(fn func()) {
-	if !w.operationalStatus.Load() {
-		return
+//export EngineEnrichLanguageDictionary
+func EngineEnrichLanguageDictionary(handle C.longlong, vocabularyPtr *C.char) C.int {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return errorStatus(err)
 	}
-	w.synchronizationGroup.Add(1)
-	w.taskQueue <- fn
+	return C.int(h.enrichDictionaryJSON(C.GoString(vocabularyPtr)))
 }
 
-func (w *ConcurrentProcessorPool) Wait() {
-	w.synchronizationGroup.Wait()
+//export GetLastError
+func GetLastError() *C.char {
+	return C.CString(lastErrorJSON())
 }
 
-func (w *ConcurrentProcessorPool) Stop() {
-	if !w.operationalStatus.Load() {
-		return
+//export EngineGetLastError
+func EngineGetLastError(handle C.longlong) *C.char {
+	h, err := lookupEngine(int64(handle))
+	if err != nil {
+		return C.CString(marshalError(err))
 	}
-	w.operationalStatus.Store(false)
-	close(w.terminationSignal)
-	close(w.taskQueue)
+	return C.CString(h.lastErrorJSON())
 }
 
-func parseOptions(jsonStr string) map[string]interface{} {
-	var options map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &options); err != nil {
-		// Retorna opções padrão em caso de erro
-		return map[string]interface{}{
-			"aggressive_mode": false,
-			"backup_files":    true,
-			"confidence_threshold": 0.8,
-		}
-	}
-	return options
+//export ReleaseAllocatedMemory
+func ReleaseAllocatedMemory(memoryPtr *C.char) {
+	C.free(unsafe.Pointer(memoryPtr))
 }
 
-func parseDictionary(data []byte) []string {
-	if len(data) == 0 {
-		return []string{}
-	}
-	
-	var words []string
-	// Assume formato binário: [4 bytes length][word][4 bytes length][word]...
-	offset := 0
-	for offset < len(data)-4 {
-		// Lê tamanho da palavra (4 bytes)
-		if offset+4 > len(data) {
-			break
-		}
-		length := int(data[offset]) | int(data[offset+1])<<8 | int(data[offset+2])<<16 | int(data[offset+3])<<24
-		offset += 4
-		
-		// Lê palavra
-		if offset+length > len(data) || length <= 0 {
-			break
-		}
-		word := string(data[offset:offset+length])
-		words = append(words, word)
-		offset += length
+//export GracefulEngineShutdown
+func GracefulEngineShutdown() {
+	ShutdownEncodingEngine(C.int(engine.DefaultShutdownTimeout / time.Millisecond))
+}
+
+// ShutdownEncodingEngine encerra a instância padrão aguardando o trabalho em
+// andamento por até timeoutMillis. Retorna 0 se tudo drenou, 1 se houve
+// cancelamento e negativo se o motor nunca foi inicializado.
+//
+//export ShutdownEncodingEngine
+func ShutdownEncodingEngine(timeoutMillis C.int) C.int {
+	defaultEngineLock.RLock()
+	h := defaultEngine
+	defaultEngineLock.RUnlock()
+	if h == nil {
+		return errorStatus(errNotInitialized())
 	}
-	
-	// Fallback para palavras básicas se não conseguir ler o binário
-	if len(words) == 0 {
-		words = []string{"ação", "não", "são", "então", "coração", "informação", "situação", "educação", "população", "administração"}
+	if !h.instance.Shutdown(time.Duration(timeoutMillis) * time.Millisecond) {
+		return C.int(1)
 	}
-	
-	return words
+	return C.int(0)
 }
 
-// parseLanguageDictionary is an alias for parseDictionary for consistency
-func parseLanguageDictionary(data []byte) []string {
-	return parseDictionary(data)
+// Funções internas
+
+// errorCString registra o erro e o devolve no envelope JSON padrão
+func errorCString(e *engine.Error) *C.char {
+	return C.CString(marshalError(recordEngineError(e)))
+}
+
+// errorStatus registra o erro e devolve o status numérico correspondente
+func errorStatus(e *engine.Error) C.int {
+	return C.int(errorStatusCode(recordEngineError(e)))
+}
+
+func errNotInitialized() *engine.Error {
+	return engine.NewError(engine.ErrCodeNotInitialized, "engine not initialized; call InitializeEncodingEngine first", nil)
+}
+
+// recordInitError registra uma falha de inicialização onde GetLastError a
+// encontra: na instância padrão anterior, se houver. Chamada com
+// defaultEngineLock adquirido.
+func recordInitError(err *engine.Error) {
+	if defaultEngine != nil {
+		defaultEngine.recordError(err)
+		return
+	}
+	recordEngineError(err)
 }
 
-func readFileOptimized(path string) (string, string) {
-	// Lê arquivo real
-	data, err := os.ReadFile(path)
+// replaceDefaultEngine cria a instância padrão; chamada com defaultEngineLock adquirido
+func replaceDefaultEngine(configJSON string) *engine.Error {
+	h, err := newEngineHandle(configJSON)
 	if err != nil {
-		return "", "unknown"
+		return err
 	}
-	
-	// Detecta encoding
-	encoding := detectEncoding(data)
-	
-	// Converte para UTF-8 se necessário
-	content := string(data)
-	if encoding != "UTF-8" {
-		content = convertToUTF8(data, encoding)
+	defaultEngine = h
+	return nil
+}
+
+// currentDefaultEngine retorna a instância padrão criada por InitializeEncodingEngine.
+// Uma instância encerrada continua sendo retornada para que as chamadas
+// recebam engine_not_running até a próxima inicialização.
+func currentDefaultEngine() (*engineHandle, *engine.Error) {
+	defaultEngineLock.RLock()
+	defer defaultEngineLock.RUnlock()
+	if defaultEngine == nil {
+		return nil, errNotInitialized()
 	}
-	
-	return content, encoding
+	return defaultEngine, nil
 }
 
-func detectEncoding(data []byte) string {
-	// Detecta BOM
-	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
-		return "UTF-8"
+// analyzeDocument valida o caminho e as opções e analisa o documento
+func (h *engineHandle) analyzeDocument(path, optionsJSON string) (*engine.Report, *engine.Error) {
+	// Validação de segurança pela política de caminhos da instância
+	resolved, pathErr := h.instance.PathPolicy().CheckFile(path)
+	if pathErr != nil {
+		return nil, h.recordError(pathErr)
+	}
+
+	options, warnings, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return nil, h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil))
+	}
+
+	// Processa com todas otimizações
+	result, err := h.instance.AnalyzeFile(context.Background(), resolved, options)
+	if err != nil {
+		return nil, h.recordError(engine.AsError(err, engine.ErrCodeIO))
 	}
-	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
-		return "UTF-16LE"
+	result.DocumentPath = path
+	result.Warnings = append(warnings, result.Warnings...)
+	return result, nil
+}
+
+// analyzeDocumentJSON analisa um documento e devolve o relatório ou o envelope de erro
+func (h *engineHandle) analyzeDocumentJSON(path, optionsJSON string) string {
+	result, analyzeErr := h.analyzeDocument(path, optionsJSON)
+	if analyzeErr != nil {
+		return marshalError(analyzeErr)
 	}
-	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
-		return "UTF-16BE"
+	return h.marshalResult(result)
+}
+
+// documentSummary resposta de AnalyzeDocumentSummary: o resumo e o id do
+// relatório completo guardado para QueryAnalysisResult
+type documentSummary struct {
+	ResultID int64 `json:"resultId"`
+	*engine.ReportSummary
+}
+
+// analyzeDocumentSummaryJSON analisa um documento, guarda o relatório
+// completo e devolve apenas o resumo
+func (h *engineHandle) analyzeDocumentSummaryJSON(path, optionsJSON string) string {
+	result, analyzeErr := h.analyzeDocument(path, optionsJSON)
+	if analyzeErr != nil {
+		return marshalError(analyzeErr)
 	}
-	
-	// Heurística simples para detectar encoding
-	validUTF8 := utf8.Valid(data)
-	if validUTF8 {
-		return "UTF-8"
+	return h.marshalResult(documentSummary{
+		ResultID:      h.results.put(result),
+		ReportSummary: result.Summary(engine.DefaultSummaryExamples),
+	})
+}
+
+// queryResultJSON devolve uma página de um relatório guardado
+func (h *engineHandle) queryResultJSON(id int64, queryJSON string) string {
+	report, lookupErr := h.results.get(id)
+	if lookupErr != nil {
+		return marshalError(h.recordError(lookupErr))
 	}
-	
-	// Verifica se parece ISO-8859-1/Windows-1252
-	hasHighBytes := false
-	for _, b := range data {
-		if b >= 128 && b <= 255 {
-			hasHighBytes = true
-			break
+
+	var query engine.ReportQuery
+	if strings.TrimSpace(queryJSON) != "" {
+		decoder := json.NewDecoder(strings.NewReader(queryJSON))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&query); err != nil {
+			return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "invalid query", map[string]interface{}{"cause": err.Error()})))
 		}
 	}
-	
-	if hasHighBytes {
-		return "ISO-8859-1"
+	page, err := report.Query(query)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeInvalidArgument)))
 	}
-	
-	return "ASCII"
+	return h.marshalResult(page)
 }
 
-func convertToUTF8(data []byte, encoding string) string {
-	switch encoding {
-	case "ISO-8859-1":
-		// Converte ISO-8859-1 para UTF-8
-		runes := make([]rune, len(data))
-		for i, b := range data {
-			runes[i] = rune(b)
-		}
-		return string(runes)
-	default:
-		return string(data)
-	}
-}
-
-func detectEncodingEncodingAnomalys(content string) []EncodingAnomaly {
-	var issues []EncodingAnomaly
-	runes := []rune(content)
-	
-	// Padrões comuns de mojibake
-	mojibakePatterns := map[string]string{
-		"Ã¡": "á", "Ã ": "à", "Ã£": "ã", "Ã¢": "â",
-		"Ã©": "é", "Ã¨": "è", "Ãª": "ê",
-		"Ã­": "í", "Ã¬": "ì", "Ã®": "î",
-		"Ã³": "ó", "Ã²": "ò", "Ãµ": "õ", "Ã´": "ô",
-		"Ãº": "ú", "Ã¹": "ù", "Ã»": "û",
-		"Ã§": "ç", "Ã±": "ñ",
-	}
-	
-	for pattern := range mojibakePatterns {
-		pos := 0
-		for {
-			index := strings.Index(content[pos:], pattern)
-			if index == -1 {
-				break
-			}
-			actualPos := pos + index
-			context := extractContext(content, actualPos, 10)
-			issues = append(issues, EncodingAnomaly{
-				AnomalyCategory: "mojibake",
-				TextPosition:    actualPos,
-				AffectedLength:  len(pattern),
-				SurroundingText: context,
-				SeverityLevel:   "high",
-			})
-			pos = actualPos + len(pattern)
-		}
+// releaseResult descarta um relatório guardado
+func (h *engineHandle) releaseResult(id int64) int {
+	if err := h.results.release(id); err != nil {
+		return errorStatusCode(h.recordError(err))
 	}
-	
-	// Detecta caracteres de substituição
-	for i, r := range runes {
-		if r == '�' || r == '?' {
-			context := extractContext(content, i, 5)
-			issues = append(issues, EncodingAnomaly{
-				AnomalyCategory: "replacement_char",
-				TextPosition:    i,
-				AffectedLength:  1,
-				SurroundingText: context,
-				SeverityLevel:   "medium",
-			})
-		}
+	return 0
+}
+
+// marshalResult serializa uma resposta com tratamento de erro
+func (h *engineHandle) marshalResult(value interface{}) string {
+	jsonResult, err := json.Marshal(value)
+	if err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeSerialization, err.Error(), nil)))
 	}
-	
-	return issues
-}
-
-func extractContext(content string, pos, radius int) string {
-	start := pos - radius
-	if start < 0 {
-		start = 0
-	}
-	end := pos + radius
-	if end > len(content) {
-		end = len(content)
-	}
-	return content[start:end]
-}
-
-func applyIntelligentTextTransformations(content string, options map[string]interface{}) []TextTransformation {
-	var corrections []TextTransformation
-	
-	// Correções baseadas em dicionário
-	mojibakeMap := map[string]string{
-		"Ã¡": "á", "Ã ": "à", "Ã£": "ã", "Ã¢": "â",
-		"Ã©": "é", "Ã¨": "è", "Ãª": "ê",
-		"Ã­": "í", "Ã¬": "ì", "Ã®": "î",
-		"Ã³": "ó", "Ã²": "ò", "Ãµ": "õ", "Ã´": "ô",
-		"Ãº": "ú", "Ã¹": "ù", "Ã»": "û",
-		"Ã§": "ç", "Ã±": "ñ",
-	}
-	
-	for broken, correct := range mojibakeMap {
-		pos := 0
-		for {
-			index := strings.Index(content[pos:], broken)
-			if index == -1 {
-				break
-			}
-			actualPos := pos + index
-			
-			// Verifica contexto usando n-gramas
-			confidence := calculateTextTransformationConfidence(content, actualPos, broken, correct)
-			
-			corrections = append(corrections, TextTransformation{
-				DocumentPosition:         actualPos,
-				OriginalSequence:         broken,
-				TransformedSequence:      correct,
-				TransformationScore:      confidence,
-				TextTransformationStrategy: "dictionary",
-			})
-			pos = actualPos + len(broken)
-		}
+	return string(jsonResult)
+}
+
+// processDocumentCollection processa um lote JSON de caminhos no pool da instância
+func (h *engineHandle) processDocumentCollection(pathsJSON, optionsJSON string) int {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		return errorStatusCode(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "paths must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
 	}
-	
-	// Correções contextuais usando dicionário
-	if dictTrie != nil {
-		words := strings.Fields(content)
-		wordPos := 0
-		for _, word := range words {
-			cleanWord := strings.ToLower(strings.Trim(word, ".,!?;:"))
-			if !dictTrie.SearchVocabulary(cleanWord) && len(cleanWord) > 2 {
-				// Tenta encontrar palavra similar no dicionário
-				if suggestion := findSimilarWord(cleanWord); suggestion != "" {
-					confidence := calculateSimilarity(cleanWord, suggestion)
-					if confidence > 0.7 {
-						corrections = append(corrections, TextTransformation{
-							DocumentPosition:         wordPos,
-							OriginalSequence:         word,
-							TransformedSequence:      suggestion,
-							TransformationScore:      confidence,
-							TextTransformationStrategy: "similarity",
-						})
-					}
-				}
-			}
-			wordPos += len(word) + 1
-		}
+
+	resolvedPaths, pathErr := h.resolvePaths(paths)
+	if pathErr != nil {
+		return errorStatusCode(pathErr)
 	}
-	
-	return corrections
-}
-
-func calculateTextTransformationConfidence(content string, pos int, original, corrected string) float64 {
-	// Confiança baseada em contexto e frequência
-	baseConfidence := 0.8
-	
-	// Verifica se a correção forma palavras válidas
-	if ngramModel != nil {
-		context := extractContext(content, pos, 3)
-		correctedContext := strings.Replace(context, original, corrected, 1)
-		
-		// Calcula probabilidade dos n-gramas
-		originalProb := ngramModel.GetProbability(context)
-		correctedProb := ngramModel.GetProbability(correctedContext)
-		
-		if correctedProb > originalProb {
-			baseConfidence += 0.1
+
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return errorStatusCode(h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)))
+	}
+
+	var failed atomic.Int64
+	err = h.instance.AnalyzeFiles(context.Background(), resolvedPaths, options, func(path string, report *engine.Report, err error) {
+		if err != nil {
+			failed.Add(1)
+			h.recordError(engine.AsError(err, engine.ErrCodeIO))
 		}
+	})
+	if err != nil {
+		return errorStatusCode(h.recordError(engine.AsError(err, engine.ErrCodeInternal)))
 	}
-	
-	return baseConfidence
+	// Falhas individuais não derrubam o lote, mas não podem passar por sucesso
+	return int(failed.Load())
 }
 
-func findSimilarWord(word string) string {
-	// Implementação simples de busca por similaridade
-	// Em uma implementação real, usaria algoritmos como Levenshtein distance
-	commonWords := []string{"ação", "não", "são", "então", "coração", "informação"}
-	
-	for _, candidate := range commonWords {
-		if calculateSimilarity(word, candidate) > 0.7 {
-			return candidate
+// resolvePaths valida todos os paths para segurança e devolve os caminhos já
+// resolvidos; o primeiro recusado é registrado como último erro
+func (h *engineHandle) resolvePaths(paths []string) ([]string, *engine.Error) {
+	resolvedPaths := make([]string, len(paths))
+	for i, path := range paths {
+		resolved, pathErr := h.instance.PathPolicy().CheckFile(path)
+		if pathErr != nil {
+			return nil, h.recordError(pathErr)
 		}
+		resolvedPaths[i] = resolved
+	}
+	return resolvedPaths, nil
+}
+
+// enrichDictionaryJSON adiciona ao dicionário uma lista JSON de palavras
+func (h *engineHandle) enrichDictionaryJSON(vocabularyJSON string) int {
+	var words []string
+	if err := json.Unmarshal([]byte(vocabularyJSON), &words); err != nil {
+		return errorStatusCode(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "vocabulary must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
 	}
-	return ""
+
+	if err := h.instance.Enrich(words); err != nil {
+		return errorStatusCode(h.recordError(engine.AsError(err, engine.ErrCodeInternal)))
+	}
+	return len(words)
+}
+
+// scanFileResult resumo de um arquivo analisado pela varredura
+type scanFileResult struct {
+	Path               string        `json:"path"`
+	Change             string        `json:"change,omitempty"`
+	Status             string        `json:"status,omitempty"`
+	SkipReason         string        `json:"skipReason,omitempty"`
+	SourceCharacterSet string        `json:"sourceCharacterSet,omitempty"`
+	AccuracyScore      float64       `json:"accuracyScore"`
+	Anomalies          int           `json:"anomalies"`
+	Corrections        int           `json:"corrections"`
+	Error              *engine.Error `json:"error,omitempty"`
+}
+
+// scanDirectoryResult resposta de ScanDirectoryConcurrently
+type scanDirectoryResult struct {
+	Root    string               `json:"root"`
+	Results []scanFileResult     `json:"results"`
+	Skipped []engine.SkippedFile `json:"skipped"`
+
+	// Preenchidos só na varredura incremental
+	Unchanged *int             `json:"unchanged,omitempty"`
+	Removed   []string         `json:"removed,omitempty"`
+	Warnings  []engine.Warning `json:"warnings,omitempty"`
+}
+
+// scanRequest opções de varredura do host. Com index, a varredura é
+// incremental e só os arquivos novos ou alterados aparecem em results.
+type scanRequest struct {
+	engine.ScanOptions
+	Index string `json:"index"`
+	Full  bool   `json:"full"`
 }
 
-func calculateSimilarity(a, b string) float64 {
-	// Implementação simples de similaridade
-	if a == b {
-		return 1.0
+func (h *engineHandle) scanDirectoryJSON(root, scanOptionsJSON, optionsJSON string) string {
+	resolvedRoot, pathErr := h.instance.PathPolicy().CheckDirectory(root)
+	if pathErr != nil {
+		return marshalError(h.recordError(pathErr))
 	}
-	
-	maxLen := len(a)
-	if len(b) > maxLen {
-		maxLen = len(b)
+
+	var request scanRequest
+	if strings.TrimSpace(scanOptionsJSON) != "" {
+		decoder := json.NewDecoder(strings.NewReader(scanOptionsJSON))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "invalid scan options", map[string]interface{}{"cause": err.Error()})))
+		}
 	}
-	
-	if maxLen == 0 {
-		return 1.0
+	scan := request.ScanOptions
+	// Os arquivos encontrados passam pela mesma política das outras exportações
+	scan.Accept = func(path string) string {
+		if _, err := h.instance.PathPolicy().CheckFile(path); err != nil {
+			if rule, _ := err.Details["rule"].(string); rule != "" {
+				return rule
+			}
+			return err.Code
+		}
+		return ""
 	}
-	
-	// Conta caracteres em comum
-	common := 0
-	for i, r := range a {
-		if i < len(b) && rune(b[i]) == r {
-			common++
+
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)))
+	}
+
+	var resultsLock sync.Mutex
+	results := []scanFileResult{}
+	visit := func(path, change string, report *engine.Report, err error) {
+		result := scanFileResult{Path: path, Change: change}
+		if err != nil {
+			result.Error = h.recordError(engine.AsError(err, engine.ErrCodeIO))
+		} else {
+			result.Status = report.Status
+			result.SkipReason = report.SkipReason
+			result.SourceCharacterSet = report.SourceCharacterSet
+			result.AccuracyScore = report.AccuracyScore
+			result.Anomalies = report.AnomalyCount
+			result.Corrections = len(report.SuggestedTransforms)
 		}
+		resultsLock.Lock()
+		results = append(results, result)
+		resultsLock.Unlock()
 	}
-	
-	return float64(common) / float64(maxLen)
-}
-
-func calculateConfidence(issues []EncodingAnomaly, corrections []TextTransformation) float64 {
-	if len(issues) == 0 {
-		return 1.0
-	}
-	
-	if len(corrections) == 0 {
-		return 0.0
-	}
-	
-	// Calcula confiança média das correções
-	totalConfidence := 0.0
-	for _, correction := range corrections {
-		totalConfidence += correction.TransformationScore
-	}
-	
-	avgConfidence := totalConfidence / float64(len(corrections))
-	
-	// Ajusta baseado na proporção de problemas corrigidos
-	correctionRatio := float64(len(corrections)) / float64(len(issues))
-	if correctionRatio > 1.0 {
-		correctionRatio = 1.0
-	}
-	
-	return avgConfidence * correctionRatio
-}
-
-// Validação de path para segurança
-func validatePath(path string) error {
-	// Previne path traversal
-	if strings.Contains(path, "..") {
-		return fmt.Errorf("path traversal detected")
-	}
-	
-	// Verifica se o arquivo existe
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %s", path)
-	}
-	
-	// Valida extensões permitidas
-	allowedExts := []string{".txt", ".log", ".csv", ".json", ".xml", ".html", ".md"}
-	lowerPath := strings.ToLower(path)
-	for _, ext := range allowedExts {
-		if strings.HasSuffix(lowerPath, ext) {
-			return nil
+
+	response := scanDirectoryResult{Root: root}
+	if request.Index != "" {
+		// O índice é gravado pelo motor: seu diretório também passa pela política
+		indexDir, pathErr := h.instance.PathPolicy().CheckDirectory(filepath.Dir(request.Index))
+		if pathErr != nil {
+			return marshalError(h.recordError(pathErr))
+		}
+		index, warnings, err := engine.LoadScanIndex(filepath.Join(indexDir, filepath.Base(request.Index)))
+		if err != nil {
+			return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+		}
+		incremental, err := h.instance.AnalyzeDirectoryIncremental(context.Background(), resolvedRoot, scan, options, index, request.Full, visit)
+		if err != nil {
+			return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeInternal)))
 		}
+		if err := index.Save(); err != nil {
+			return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+		}
+		response.Skipped = incremental.Skipped
+		response.Unchanged = &incremental.Unchanged
+		response.Removed = incremental.Removed
+		response.Warnings = append(warnings, incremental.Warnings...)
+	} else {
+		scanned, err := h.instance.AnalyzeDirectory(context.Background(), resolvedRoot, scan, options, func(path string, report *engine.Report, err error) {
+			visit(path, "", report, err)
+		})
+		if err != nil {
+			return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeInternal)))
+		}
+		response.Skipped = scanned.Skipped
 	}
-	return fmt.Errorf("file type not allowed. Supported: %v", allowedExts)
+
+	// Ordem estável independente da ordem de conclusão dos workers
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	response.Results = results
+	return h.marshalResult(response)
 }
 
 func main() {
 	// Necessário para compilar como biblioteca compartilhada
-}
\ No newline at end of file
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"demojibake/engine"
+)
+
+// runAnalyze imprime o relatório completo de cada arquivo
+func runAnalyze(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var stream, summary bool
+	var chunkSize, examples int
+	fs := newFlagSet("analyze", "[flags] arquivo... | -", stderr)
+	flags.register(fs)
+	fs.BoolVar(&stream, "stream", false, "analisa em trechos com memória limitada, emitindo os resultados aos poucos")
+	fs.IntVar(&chunkSize, "chunk-size", engine.DefaultStreamChunkSize, "tamanho dos trechos do modo --stream, em bytes")
+	fs.BoolVar(&summary, "summary", false, "mostra só as contagens por categoria, severidade e estratégia e alguns exemplos")
+	fs.IntVar(&examples, "examples", engine.DefaultSummaryExamples, "exemplos de anomalias e correções no modo --summary")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	if summary && (stream || fs.Arg(0) == "-") {
+		return reportError(stderr, formatText, engine.NewError(engine.ErrCodeInvalidArgument, "--summary cannot be combined with --stream or stdin", nil))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+
+	if stream || fs.Arg(0) == "-" {
+		return runAnalyzeStream(e, options, warnings, &flags, fs.Args(), engine.StreamOptions{ChunkSize: chunkSize}, stdout, stderr)
+	}
+
+	exitCode := exitClean
+	var reports []*engine.Report
+	for _, path := range fs.Args() {
+		report, err := e.AnalyzeFile(context.Background(), path, options)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		report.Warnings = append(report.Warnings, warnings...)
+		reports = append(reports, report)
+		if code := exitCodeFor(report); code > exitCode {
+			exitCode = code
+		}
+	}
+
+	if summary {
+		summaries := make([]*engine.ReportSummary, len(reports))
+		for i, report := range reports {
+			summaries[i] = report.Summary(examples)
+		}
+		if flags.format == formatJSON {
+			if len(summaries) == 1 {
+				writeJSON(stdout, summaries[0])
+			} else {
+				writeJSON(stdout, summaries)
+			}
+			return exitCode
+		}
+		printWarnings(stderr, warnings)
+		for i, summary := range summaries {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			printSummary(stdout, summary)
+		}
+		return exitCode
+	}
+
+	if flags.format == formatJSON {
+		if len(reports) == 1 {
+			writeJSON(stdout, reports[0])
+		} else {
+			writeJSON(stdout, reports)
+		}
+		return exitCode
+	}
+
+	printWarnings(stderr, warnings)
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printReport(stdout, report)
+	}
+	return exitCode
+}
+
+// runAnalyzeStream analisa cada arquivo (ou a entrada padrão, com "-") em
+// fluxo. Em JSON a saída é JSON Lines: um objeto por trecho com achados e um
+// resumo por arquivo.
+func runAnalyzeStream(e *engine.Engine, options engine.Options, warnings []engine.Warning, flags *commonFlags, paths []string, stream engine.StreamOptions, stdout, stderr io.Writer) int {
+	if flags.format == formatText {
+		printWarnings(stderr, warnings)
+	}
+
+	exitCode := exitClean
+	for _, path := range paths {
+		visit := func(chunk engine.StreamChunk) {
+			if len(chunk.EncodingAnomalies) == 0 && len(chunk.SuggestedTransforms) == 0 {
+				return
+			}
+			if flags.format == formatJSON {
+				writeJSONLine(stdout, streamChunkLine{Path: path, Chunk: chunk})
+				return
+			}
+			for _, anomaly := range chunk.EncodingAnomalies {
+				fmt.Fprintf(stdout, "%s:%d: %s %s %q\n", path, anomaly.TextPosition, anomaly.AnomalyCategory, anomaly.SeverityLevel, anomaly.SurroundingText)
+			}
+			for _, t := range chunk.SuggestedTransforms {
+				fmt.Fprintf(stdout, "%s:%d: %q -> %q (%.2f, %s)\n", path, t.DocumentPosition, t.OriginalSequence, t.TransformedSequence, t.TransformationScore, t.TextTransformationStrategy)
+			}
+		}
+
+		var summary *engine.StreamSummary
+		var err error
+		if path == "-" {
+			summary, err = e.AnalyzeStream(context.Background(), os.Stdin, options, stream, visit)
+		} else {
+			summary, err = e.AnalyzeFileStream(context.Background(), path, options, stream, visit)
+		}
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+
+		if flags.format == formatJSON {
+			writeJSONLine(stdout, streamSummaryLine{Summary: summary})
+		} else if summary.Status == engine.StatusSkipped {
+			fmt.Fprintf(stdout, "%s: ignorado (%s)\n", path, describeSkip(summary.SkipReason, summary.ContentClass))
+		} else {
+			printWarnings(stderr, summary.Warnings)
+			fmt.Fprintf(stdout, "%s: %s, %d bytes em %d trechos, %d anomalias, %d correções, confiança %.2f\n",
+				path, summary.SourceCharacterSet, summary.BytesRead, summary.Chunks, summary.Anomalies, summary.Corrections, summary.AccuracyScore)
+		}
+		if summary.Anomalies > 0 || summary.Corrections > 0 {
+			exitCode = exitAnomalies
+		}
+	}
+	return exitCode
+}
+
+// streamChunkLine linha JSON com os achados de um trecho
+type streamChunkLine struct {
+	Path  string             `json:"path"`
+	Chunk engine.StreamChunk `json:"chunk"`
+}
+
+// streamSummaryLine linha JSON com o resumo de um arquivo
+type streamSummaryLine struct {
+	Summary *engine.StreamSummary `json:"summary"`
+}
+
+// printReport imprime o relatório em formato legível
+func printReport(w io.Writer, report *engine.Report) {
+	fmt.Fprintf(w, "%s\n", report.DocumentPath)
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(w, "  aviso:      %s\n", warning.Message)
+	}
+	if report.Status == engine.StatusSkipped {
+		fmt.Fprintf(w, "  ignorado:   %s\n", describeSkip(report.SkipReason, report.ContentClass))
+		return
+	}
+	fmt.Fprintf(w, "  encoding:   %s\n", report.SourceCharacterSet)
+	fmt.Fprintf(w, "  confiança:  %.2f\n", report.AccuracyScore)
+	if report.AnomaliesTruncated {
+		fmt.Fprintf(w, "  anomalias:  %d (%d registradas)\n", report.AnomalyCount, len(report.EncodingAnomalies))
+	} else {
+		fmt.Fprintf(w, "  anomalias:  %d\n", report.AnomalyCount)
+	}
+	for _, anomaly := range report.EncodingAnomalies {
+		fmt.Fprintf(w, "    %6d  %-16s %-6s %q\n", anomaly.TextPosition, anomaly.AnomalyCategory, anomaly.SeverityLevel, anomaly.SurroundingText)
+	}
+	fmt.Fprintf(w, "  correções:  %d\n", len(report.SuggestedTransforms))
+	for _, t := range report.SuggestedTransforms {
+		fmt.Fprintf(w, "    %6d  %s  %q -> %q  (%.2f, %s)\n", t.DocumentPosition, t.ID, t.OriginalSequence, t.TransformedSequence, t.TransformationScore, t.TextTransformationStrategy)
+	}
+	if len(report.RejectedTransforms) > 0 {
+		fmt.Fprintf(w, "  rejeitadas: %d\n", len(report.RejectedTransforms))
+	}
+}
+
+// printSummary imprime o resumo de um relatório em texto
+func printSummary(w io.Writer, summary *engine.ReportSummary) {
+	fmt.Fprintf(w, "%s\n", summary.DocumentPath)
+	for _, warning := range summary.Warnings {
+		fmt.Fprintf(w, "  aviso:      %s\n", warning.Message)
+	}
+	if summary.Status == engine.StatusSkipped {
+		fmt.Fprintf(w, "  ignorado:   %s\n", summary.SkipReason)
+		return
+	}
+	fmt.Fprintf(w, "  encoding:   %s\n", summary.SourceCharacterSet)
+	fmt.Fprintf(w, "  confiança:  %.2f\n", summary.AccuracyScore)
+	fmt.Fprintf(w, "  anomalias:  %d%s\n", summary.AnomalyCount, formatCounts(summary.ByCategory))
+	if len(summary.BySeverity) > 0 {
+		fmt.Fprintf(w, "  severidade:%s\n", formatCounts(summary.BySeverity))
+	}
+	for _, anomaly := range summary.TopAnomalies {
+		fmt.Fprintf(w, "    %6d  %-16s %-6s %q\n", anomaly.TextPosition, anomaly.AnomalyCategory, anomaly.SeverityLevel, anomaly.SurroundingText)
+	}
+	fmt.Fprintf(w, "  correções:  %d%s\n", summary.TransformationCount, formatCounts(summary.ByStrategy))
+	for _, t := range summary.TopTransforms {
+		fmt.Fprintf(w, "    %6d  %s  %q -> %q  (%.2f, %s)\n", t.DocumentPosition, t.ID, t.OriginalSequence, t.TransformedSequence, t.TransformationScore, t.TextTransformationStrategy)
+	}
+	if summary.RejectedCount > 0 {
+		fmt.Fprintf(w, "  rejeitadas: %d\n", summary.RejectedCount)
+	}
+}
+
+// formatCounts formata contagens como " (a: 1, b: 2)", em ordem alfabética
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", key, counts[key])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
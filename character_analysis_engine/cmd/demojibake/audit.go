@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"demojibake/engine"
+)
+
+// runAudit confere o log de auditoria: "verify [log]" recalcula o hash de
+// cada arquivo registrado e confere a cadeia do log
+func runAudit(args []string, stdout, stderr io.Writer) int {
+	var format, auditLog string
+	fs := newFlagSet("audit", "verify [flags] [log]", stderr)
+	fs.StringVar(&format, "format", formatText, "formato de saída: text ou json")
+	fs.StringVar(&auditLog, "audit-log", "", "log JSONL a conferir")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	subcommand := fs.Arg(0)
+	if ok, code := parseFlags(fs, fs.Args()[1:]); !ok {
+		return code
+	}
+	if format != formatText && format != formatJSON {
+		return reportError(stderr, formatText, engine.NewError(engine.ErrCodeInvalidArgument, "unknown output format", map[string]interface{}{"format": format}))
+	}
+	if subcommand != "verify" {
+		return reportError(stderr, format, engine.NewError(engine.ErrCodeInvalidArgument, "unknown audit subcommand", map[string]interface{}{"subcommand": subcommand}))
+	}
+	if fs.NArg() == 1 {
+		auditLog = fs.Arg(0)
+	}
+	if auditLog == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	result, err := engine.VerifyAuditLog(auditLog)
+	if err != nil {
+		return reportError(stderr, format, err)
+	}
+	if format == formatJSON {
+		writeJSON(stdout, result)
+	} else {
+		for _, file := range result.Files {
+			switch file.Status {
+			case engine.AuditModified:
+				fmt.Fprintf(stdout, "%s: alterado depois do último registro (esperado %s, atual %s)\n", file.Path, file.Expected[:12], file.Actual[:12])
+			case engine.AuditMissing:
+				fmt.Fprintf(stdout, "%s: ausente\n", file.Path)
+			}
+		}
+		if !result.ChainOK {
+			fmt.Fprintf(stdout, "cadeia do log quebrada nas linhas %v\n", result.BrokenAt)
+		}
+		fmt.Fprintf(stdout, "%d registros, %d arquivos: %d conferem, %d alterados, %d ausentes\n",
+			result.Records, len(result.Files), result.Matched, result.Modified, result.Missing)
+	}
+	if !result.OK() {
+		return exitAnomalies
+	}
+	return exitClean
+}
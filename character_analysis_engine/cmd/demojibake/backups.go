@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"demojibake/engine"
+)
+
+// runBackups consulta o repositório de backups: "list [arquivo]",
+// "restore arquivo" ou "prune"
+func runBackups(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var config engine.BackupStoreConfig
+	var at string
+	fs := newFlagSet("backups", "list [flags] [arquivo] | restore [flags] arquivo | prune [flags]", stderr)
+	fs.StringVar(&flags.format, "format", formatText, "formato de saída: text ou json")
+	flags.registerWrite(fs)
+	fs.StringVar(&at, "at", "", "com restore, a versão guardada até este momento (RFC 3339 ou AAAA-MM-DD[THH:MM])")
+	fs.IntVar(&config.MaxAgeDays, "max-age-days", 0, "descarta backups mais antigos que isso (0 sem limite)")
+	fs.Int64Var(&config.MaxBytes, "max-bytes", 0, "descarta os backups mais antigos acima deste total (0 sem limite)")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	subcommand := fs.Arg(0)
+	if ok, code := parseFlags(fs, fs.Args()[1:]); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+
+	config.Dir = flags.backupDir
+	store, err := engine.OpenBackupStore(config)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+
+	switch subcommand {
+	case "list":
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return exitError
+		}
+		entries, err := store.List(fs.Arg(0))
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, entries)
+			return exitClean
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(stdout, "%s  %s  %d bytes  %s\n", entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Hash[:12], entry.Size, entry.Path)
+		}
+		return exitClean
+
+	case "restore":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return exitError
+		}
+		var when time.Time
+		if at != "" {
+			if when, err = engine.ParseBackupTime(at); err != nil {
+				return reportError(stderr, flags.format, err)
+			}
+		}
+		audit, err := flags.openAuditLog()
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		entry, err := store.Restore(fs.Arg(0), when, audit)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, entry)
+			return exitClean
+		}
+		fmt.Fprintf(stdout, "%s: restaurado da versão de %s\n", entry.Path, entry.Time.Local().Format("2006-01-02 15:04:05"))
+		return exitClean
+
+	case "prune":
+		result, err := store.Prune()
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, result)
+			return exitClean
+		}
+		fmt.Fprintf(stdout, "%d backups descartados, %d bytes liberados; restam %d backups (%d bytes)\n",
+			result.RemovedEntries, result.FreedBytes, result.Entries, result.Bytes)
+		return exitClean
+	}
+
+	return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "unknown backups subcommand", map[string]interface{}{"subcommand": subcommand}))
+}
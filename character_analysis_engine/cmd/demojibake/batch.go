@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"demojibake/engine"
+)
+
+// batchResult resultado de um arquivo no lote
+type batchResult struct {
+	Path        string        `json:"path"`
+	Change      string        `json:"change,omitempty"`
+	Status      string        `json:"status,omitempty"`
+	SkipReason  string        `json:"skipReason,omitempty"`
+	Encoding    string        `json:"encoding,omitempty"`
+	Anomalies   int           `json:"anomalies"`
+	Corrections int           `json:"corrections"`
+	FromCache   bool          `json:"fromCache,omitempty"`
+	Error       *engine.Error `json:"error,omitempty"`
+}
+
+// batchSummary totais do lote
+type batchSummary struct {
+	Files       int                  `json:"files"`
+	Clean       int                  `json:"clean"`
+	WithIssues  int                  `json:"withIssues"`
+	Failed      int                  `json:"failed"`
+	NotAnalyzed int                  `json:"notAnalyzed"`
+	Anomalies   int                  `json:"anomalies"`
+	Corrections int                  `json:"corrections"`
+	Cached      int                  `json:"cached"`
+	Results     []batchResult        `json:"results"`
+	Skipped     []engine.SkippedFile `json:"skipped,omitempty"`
+
+	// Preenchidos só com --index
+	Unchanged *int     `json:"unchanged,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+}
+
+// stringList flag repetível
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runBatch analisa vários arquivos em paralelo usando o pool do motor
+func runBatch(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var listFile, dir, indexFile string
+	var full bool
+	var scan engine.ScanOptions
+	fs := newFlagSet("batch", "[flags] [arquivo...]", stderr)
+	flags.register(fs)
+	fs.StringVar(&listFile, "list", "", "arquivo com um caminho por linha (- para stdin)")
+	fs.IntVar(&flags.workers, "workers", 0, "número de workers (0 usa o número de CPUs)")
+	fs.StringVar(&dir, "dir", "", "varre este diretório recursivamente")
+	fs.Var((*stringList)(&scan.Include), "include", "padrão glob de arquivos a incluir na varredura (repetível)")
+	fs.Var((*stringList)(&scan.Exclude), "exclude", "padrão glob de arquivos e diretórios a excluir (repetível)")
+	fs.IntVar(&scan.MaxDepth, "max-depth", 0, "profundidade máxima da varredura (0 sem limite)")
+	fs.Int64Var(&scan.MaxFileSize, "max-size", 0, "ignora arquivos maiores que este número de bytes (0 sem limite)")
+	fs.BoolVar(&scan.Gitignore, "gitignore", false, "respeita os arquivos .gitignore na varredura")
+	fs.StringVar(&indexFile, "index", "", "índice da varredura incremental: só arquivos novos ou alterados são analisados e listados")
+	fs.BoolVar(&full, "full", false, "com --index, reanalisa todos os arquivos")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+
+	paths := fs.Args()
+	if listFile != "" {
+		listed, err := readPathList(listFile)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		paths = append(paths, listed...)
+	}
+	if len(paths) == 0 && dir == "" {
+		fs.Usage()
+		return exitError
+	}
+	if indexFile != "" && (dir == "" || len(paths) > 0) {
+		return reportError(stderr, formatText, engine.NewError(engine.ErrCodeInvalidArgument, "--index requires --dir and no other paths", nil))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	if flags.format == formatText {
+		printWarnings(stderr, warnings)
+	}
+
+	var resultsLock sync.Mutex
+	results := make([]batchResult, 0, len(paths))
+	visitChange := func(path, change string, report *engine.Report, err error) {
+		result := batchResult{Path: path, Change: change}
+		if err != nil {
+			result.Error = engine.AsError(err, engine.ErrCodeInternal)
+		} else {
+			result.Status = report.Status
+			result.SkipReason = report.SkipReason
+			result.Encoding = report.SourceCharacterSet
+			result.Anomalies = report.AnomalyCount
+			result.Corrections = len(report.SuggestedTransforms)
+			result.FromCache = report.FromCache
+		}
+		resultsLock.Lock()
+		results = append(results, result)
+		resultsLock.Unlock()
+	}
+	visit := func(path string, report *engine.Report, err error) {
+		visitChange(path, "", report, err)
+	}
+	if len(paths) > 0 {
+		if err := e.AnalyzeFiles(context.Background(), paths, options, visit); err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+	}
+	var skipped []engine.SkippedFile
+	var incremental *engine.IncrementalResult
+	switch {
+	case indexFile != "":
+		index, indexWarnings, err := engine.LoadScanIndex(indexFile)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		incremental, err = e.AnalyzeDirectoryIncremental(context.Background(), dir, scan, options, index, full, visitChange)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if err := index.Save(); err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatText {
+			printWarnings(stderr, append(indexWarnings, incremental.Warnings...))
+		}
+		skipped = incremental.Skipped
+	case dir != "":
+		scanned, err := e.AnalyzeDirectory(context.Background(), dir, scan, options, visit)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		skipped = scanned.Skipped
+	}
+
+	// Ordem estável independente da ordem de conclusão dos workers
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	summary := batchSummary{Files: len(results), Results: results, Skipped: skipped}
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			summary.Failed++
+		case result.Status == engine.StatusSkipped:
+			summary.NotAnalyzed++
+		case result.Anomalies > 0 || result.Corrections > 0:
+			summary.WithIssues++
+		default:
+			summary.Clean++
+		}
+		summary.Anomalies += result.Anomalies
+		summary.Corrections += result.Corrections
+		if result.FromCache {
+			summary.Cached++
+		}
+	}
+	if incremental != nil {
+		summary.Unchanged = &incremental.Unchanged
+		summary.Removed = incremental.Removed
+	}
+
+	if flags.format == formatJSON {
+		writeJSON(stdout, summary)
+	} else {
+		for _, result := range results {
+			if result.Error != nil {
+				fmt.Fprintf(stdout, "%s: erro: %s\n", result.Path, result.Error.Message)
+				continue
+			}
+			if result.Status == engine.StatusSkipped {
+				fmt.Fprintf(stdout, "%s:%s não analisado (%s)\n", result.Path, describeChange(result.Change), result.SkipReason)
+				continue
+			}
+			fmt.Fprintf(stdout, "%s:%s %s, %d anomalias, %d correções\n", result.Path, describeChange(result.Change), result.Encoding, result.Anomalies, result.Corrections)
+		}
+		for _, removed := range summary.Removed {
+			fmt.Fprintf(stdout, "%s: removido\n", removed)
+		}
+		for _, skip := range skipped {
+			if skip.Detail != "" {
+				fmt.Fprintf(stdout, "%s: ignorado (%s: %s)\n", skip.Path, skip.Reason, skip.Detail)
+				continue
+			}
+			fmt.Fprintf(stdout, "%s: ignorado (%s)\n", skip.Path, skip.Reason)
+		}
+		fmt.Fprintf(stdout, "\n%d arquivos: %d limpos, %d com problemas, %d com erro, %d não analisados, %d ignorados (%d anomalias, %d correções)\n",
+			summary.Files, summary.Clean, summary.WithIssues, summary.Failed, summary.NotAnalyzed, len(skipped), summary.Anomalies, summary.Corrections)
+		if summary.Cached > 0 {
+			fmt.Fprintf(stdout, "%d relatórios reaproveitados do cache\n", summary.Cached)
+		}
+		if incremental != nil {
+			fmt.Fprintf(stdout, "índice: %d novos, %d alterados, %d inalterados, %d removidos\n",
+				incremental.Added, incremental.Modified, incremental.Unchanged, len(incremental.Removed))
+		}
+	}
+
+	switch {
+	case summary.Failed > 0:
+		return exitError
+	case summary.WithIssues > 0:
+		return exitAnomalies
+	}
+	return exitClean
+}
+
+// describeChange rótulo da situação do arquivo no índice incremental
+func describeChange(change string) string {
+	switch change {
+	case engine.ChangeAdded:
+		return " novo,"
+	case engine.ChangeModified:
+		return " alterado,"
+	}
+	return ""
+}
+
+// readPathList lê uma lista de caminhos, um por linha; linhas vazias e
+// comentários (#) são ignorados
+func readPathList(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, engine.NewError(engine.ErrCodeIO, "failed to open path list", map[string]interface{}{"path": name, "cause": err.Error()})
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, engine.NewError(engine.ErrCodeIO, "failed to read path list", map[string]interface{}{"path": name, "cause": err.Error()})
+	}
+	return paths, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"demojibake/engine"
+)
+
+// runConfig mostra a configuração efetiva de cada arquivo: os
+// .demojibake.json encontrados, as sobreposições que casam e as opções
+// resultantes, já com --options e as flags de atalho por cima
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	fs := newFlagSet("config", "[flags] arquivo...", stderr)
+	flags.register(fs)
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	if flags.format == formatText {
+		printWarnings(stderr, warnings)
+	}
+
+	var configs []*engine.EffectiveConfig
+	for _, path := range fs.Args() {
+		if flags.noProject {
+			configs = append(configs, &engine.EffectiveConfig{Path: path, Sources: []string{}, Overrides: []string{}, Options: options})
+			continue
+		}
+		effective, err := e.EffectiveConfig(path, options)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		configs = append(configs, effective)
+	}
+	if flags.format == formatJSON {
+		writeJSON(stdout, configs)
+		return exitClean
+	}
+	for _, effective := range configs {
+		fmt.Fprintf(stdout, "%s\n", effective.Path)
+		if len(effective.Sources) == 0 {
+			fmt.Fprintf(stdout, "  nenhum %s encontrado\n", engine.ProjectConfigFile)
+		}
+		for _, source := range effective.Sources {
+			fmt.Fprintf(stdout, "  arquivo     %s\n", source)
+		}
+		for _, override := range effective.Overrides {
+			fmt.Fprintf(stdout, "  sobreposto  %s\n", override)
+		}
+		if effective.Ignored {
+			fmt.Fprintf(stdout, "  ignorado por %s\n", effective.IgnoredBy)
+		}
+		if effective.Vocabulary > 0 {
+			fmt.Fprintf(stdout, "  vocabulário %d palavras\n", effective.Vocabulary)
+		}
+		o := effective.Options
+		fmt.Fprintf(stdout, "  confidence_threshold=%g aggressive_mode=%t fixMojibake=%t useDictionary=%t\n", o.ConfidenceThreshold, o.AggressiveMode, o.FixMojibake, o.UseDictionary)
+		fmt.Fprintf(stdout, "  backup_files=%t parallel=%t max_file_size=%d max_anomalies=%d timeout_ms=%d\n", o.BackupFiles, o.Parallel, o.MaxFileSize, o.MaxAnomalies, o.TimeoutMillis)
+		for _, w := range effective.Warnings {
+			fmt.Fprintf(stdout, "  aviso: %s\n", w.Message)
+		}
+	}
+	return exitClean
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"demojibake/engine"
+)
+
+// detectResult resultado resumido da detecção de um arquivo
+type detectResult struct {
+	Path       string         `json:"path"`
+	Status     string         `json:"status"`
+	Content    string         `json:"content"`
+	Encoding   string         `json:"encoding,omitempty"`
+	Anomalies  int            `json:"anomalies"`
+	Categories map[string]int `json:"categories"`
+}
+
+// runDetect detecta o encoding e conta as anomalias, sem gerar correções
+func runDetect(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	fs := newFlagSet("detect", "[flags] arquivo...", stderr)
+	flags.register(fs)
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, _, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	// Só a detecção interessa aqui
+	options.FixMojibake = false
+	options.UseDictionary = false
+	options.AggressiveMode = false
+
+	exitCode := exitClean
+	var results []detectResult
+	for _, path := range fs.Args() {
+		report, err := e.AnalyzeFile(context.Background(), path, options)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		results = append(results, detectResult{
+			Path:       path,
+			Status:     report.Status,
+			Content:    describeSkip(report.ContentClass.Class, report.ContentClass),
+			Encoding:   report.SourceCharacterSet,
+			Anomalies:  report.AnomalyCount,
+			Categories: anomalyCounts(report),
+		})
+		if code := exitCodeFor(report); code > exitCode {
+			exitCode = code
+		}
+	}
+
+	if flags.format == formatJSON {
+		if len(results) == 1 {
+			writeJSON(stdout, results[0])
+		} else {
+			writeJSON(stdout, results)
+		}
+		return exitCode
+	}
+
+	for _, result := range results {
+		if result.Status == engine.StatusSkipped {
+			fmt.Fprintf(stdout, "%s: ignorado (%s)\n", result.Path, result.Content)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s, %d anomalias", result.Path, result.Encoding, result.Anomalies)
+		categories := make([]string, 0, len(result.Categories))
+		for category := range result.Categories {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			fmt.Fprintf(stdout, " [%s: %d]", category, result.Categories[category])
+		}
+		fmt.Fprintln(stdout)
+	}
+	return exitCode
+}
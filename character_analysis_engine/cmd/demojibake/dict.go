@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"demojibake/engine"
+)
+
+// runDict consulta o dicionário: "stats" ou "lookup palavra..."
+func runDict(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	fs := newFlagSet("dict", "[flags] stats | lookup palavra...", stderr)
+	flags.register(fs)
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	switch fs.Arg(0) {
+	case "stats":
+		stats := e.Metrics()
+		if flags.format == formatJSON {
+			writeJSON(stdout, stats)
+			return exitClean
+		}
+		keys := make([]string, 0, len(stats))
+		for key := range stats {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(stdout, "%-30s %v\n", key, stats[key])
+		}
+		return exitClean
+
+	case "lookup":
+		words := fs.Args()[1:]
+		if len(words) == 0 {
+			fs.Usage()
+			return exitError
+		}
+		// Palavras ausentes contam como "anomalia" para scripts
+		exitCode := exitClean
+		found := make(map[string]bool, len(words))
+		for _, word := range words {
+			found[word] = e.Contains(word)
+			if !found[word] {
+				exitCode = exitAnomalies
+			}
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, found)
+			return exitCode
+		}
+		for _, word := range words {
+			status := "ausente"
+			if found[word] {
+				status = "presente"
+			}
+			fmt.Fprintf(stdout, "%s: %s\n", word, status)
+		}
+		return exitCode
+	}
+
+	return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "unknown dict subcommand", map[string]interface{}{"subcommand": fs.Arg(0)}))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"demojibake/engine"
+)
+
+// runDiff mostra as correções sugeridas como diff unificado, sem gravar
+// nada. Com --git a saída de vários arquivos é um patch para git apply.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	diffOptions := engine.DefaultDiffOptions()
+	fs := newFlagSet("diff", "[flags] arquivo...", stderr)
+	flags.register(fs)
+	fs.IntVar(&diffOptions.Context, "context", engine.DefaultDiffContext, "linhas de contexto em volta de cada alteração")
+	fs.IntVar(&diffOptions.Context, "U", engine.DefaultDiffContext, "o mesmo que --context")
+	fs.BoolVar(&diffOptions.Git, "git", false, "cabeçalhos no formato de git diff, aplicáveis com git apply")
+	fs.StringVar(&diffOptions.Root, "root", "", "com --git, caminhos relativos a este diretório (padrão: o atual)")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	if diffOptions.Context < 0 {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "--context must not be negative", nil))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	if flags.format == formatText {
+		printWarnings(stderr, warnings)
+	}
+
+	patch, err := e.DiffFiles(context.Background(), fs.Args(), options, diffOptions)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	exitCode := exitClean
+	if patch.Patch != "" {
+		exitCode = exitAnomalies
+	}
+	if flags.format == formatJSON {
+		writeJSON(stdout, patch)
+		return exitCode
+	}
+	for _, file := range patch.Files {
+		if file.SkipReason != "" {
+			fmt.Fprintf(stderr, "%s: não analisado (%s)\n", file.Path, file.SkipReason)
+		}
+	}
+	io.WriteString(stdout, patch.Patch)
+	return exitCode
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"demojibake/engine"
+)
+
+// runFeedback registra, mostra e apaga o aprendizado com as decisões dos
+// revisores: "record [--accept IDS] [--reject IDS] arquivo", "show" e
+// "reset [--strategy NOME]"
+func runFeedback(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var accept, reject, analysis, strategy string
+	fs := newFlagSet("feedback", "record [flags] arquivo | show | reset [--strategy NOME]", stderr)
+	flags.register(fs)
+	fs.StringVar(&accept, "accept", "", "com record, IDs das correções aceitas, separados por vírgula")
+	fs.StringVar(&reject, "reject", "", "com record, IDs das correções rejeitadas, separados por vírgula")
+	fs.StringVar(&analysis, "analysis", "", "com record, relatório de analyze --format json em que os IDs foram escolhidos")
+	fs.StringVar(&strategy, "strategy", "", "com reset, apaga só o aprendido para esta estratégia")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	subcommand := fs.Arg(0)
+	if ok, code := parseFlags(fs, fs.Args()[1:]); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if flags.feedback == "" {
+		flags.feedback = defaultFeedbackFile()
+	}
+	if flags.feedback == "" {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "feedback file not configured", nil))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	switch subcommand {
+	case "record":
+		if fs.NArg() != 1 || (accept == "" && reject == "") {
+			fs.Usage()
+			return exitError
+		}
+		options, _, err := flags.analysisOptions(e, fs)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		path := fs.Arg(0)
+		report, err := loadAnalysis(e, path, analysis, options)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		result, err := e.RecordFeedback(context.Background(), path, report, splitIDs(accept), splitIDs(reject))
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, result)
+		} else {
+			for _, id := range result.Unknown {
+				fmt.Fprintf(stderr, "%s: correção %s desconhecida\n", path, id)
+			}
+			for _, id := range result.Mismatched {
+				fmt.Fprintf(stderr, "%s: correção %s não confere com o arquivo\n", path, id)
+			}
+			fmt.Fprintf(stdout, "%s: %d aceitas, %d rejeitadas registradas em %s\n", path, result.Accepted, result.Rejected, e.Feedback().Path())
+		}
+		if len(result.Unknown) > 0 || len(result.Mismatched) > 0 {
+			return exitError
+		}
+		return exitClean
+	case "show":
+	case "reset":
+		if err := e.ResetFeedback(strategy); err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+	default:
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "unknown feedback subcommand", map[string]interface{}{"subcommand": subcommand}))
+	}
+
+	summary, err := e.LearnedFeedback()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	if flags.format == formatJSON {
+		writeJSON(stdout, summary)
+		return exitClean
+	}
+	fmt.Fprintf(stdout, "%s: %d decisões\n", summary.Path, summary.Decisions)
+	for _, s := range summary.Strategies {
+		fmt.Fprintf(stdout, "  %-12s %4d aceitas %4d rejeitadas  ajuste %+.3f\n", s.Strategy, s.Accepted, s.Rejected, s.Adjustment)
+	}
+	for _, p := range summary.Patterns {
+		fmt.Fprintf(stdout, "    %q -> %q em %q (%s): %d aceitas, %d rejeitadas, ajuste %+.3f\n", p.Original, p.Replacement, p.Context, p.Strategy, p.Accepted, p.Rejected, p.Adjustment)
+	}
+	return exitClean
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"demojibake/engine"
+)
+
+// filterFlags flags do modo filtro, no estilo do iconv
+type filterFlags struct {
+	from    string
+	to      string
+	report  string
+	replace bool
+}
+
+// register registra as flags do modo filtro
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.from, "from", engine.CharsetAuto, "charset de entrada (auto, utf-8, latin1, cp1252, ascii)")
+	fs.StringVar(&f.to, "to", engine.CharsetUTF8, "charset de saída (utf-8, latin1, cp1252, ascii)")
+	fs.StringVar(&f.report, "report", "", "grava o relatório neste arquivo em vez do stderr")
+	fs.BoolVar(&f.replace, "replace", false, "substitui caracteres inválidos ou sem representação em vez de falhar")
+}
+
+// requested informa se o modo filtro foi pedido: entrada "-" ou --from/--to
+func (f *filterFlags) requested(fs *flag.FlagSet) bool {
+	if fs.Arg(0) == "-" {
+		return true
+	}
+	requested := false
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "from" || fl.Name == "to" {
+			requested = true
+		}
+	})
+	return requested
+}
+
+// filterReportSummary última linha do relatório JSON do modo filtro
+type filterReportSummary struct {
+	Summary  *engine.FilterSummary `json:"summary"`
+	Warnings []engine.Warning      `json:"warnings,omitempty"`
+}
+
+// runFilter corrige um fluxo linha a linha: o texto vai para stdout e o
+// relatório para o stderr ou para o arquivo de --report
+func runFilter(flags *commonFlags, filter *filterFlags, fs *flag.FlagSet, stdout, stderr io.Writer) int {
+	var input io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeIO, "failed to open document", map[string]interface{}{"path": name, "cause": err.Error()}))
+		}
+		defer file.Close()
+		input = file
+	}
+
+	reportWriter := stderr
+	if filter.report != "" {
+		file, err := os.Create(filter.report)
+		if err != nil {
+			return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeIO, "failed to create report", map[string]interface{}{"path": filter.report, "cause": err.Error()}))
+		}
+		defer file.Close()
+		reportWriter = file
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+
+	// Em JSON o relatório é JSON Lines: uma linha por linha corrigida e o
+	// resumo no final, para que possa ser consumido durante a execução
+	visit := func(line engine.LineReport) {
+		if flags.format == formatJSON {
+			writeJSONLine(reportWriter, line)
+			return
+		}
+		for _, t := range line.AppliedTransforms {
+			fmt.Fprintf(reportWriter, "linha %d:%d: %q -> %q (%.2f, %s)\n", line.Line, t.DocumentPosition, t.OriginalSequence, t.TransformedSequence, t.TransformationScore, t.TextTransformationStrategy)
+		}
+		if len(line.AppliedTransforms) == 0 {
+			fmt.Fprintf(reportWriter, "linha %d: %d anomalias sem correção\n", line.Line, len(line.EncodingAnomalies))
+		}
+	}
+
+	if flags.format == formatText {
+		printWarnings(reportWriter, warnings)
+	}
+	summary, err := e.Filter(context.Background(), input, stdout, options, engine.FilterOptions{
+		From:       filter.from,
+		To:         filter.to,
+		Substitute: filter.replace,
+	}, visit)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+
+	if flags.format == formatJSON {
+		writeJSONLine(reportWriter, filterReportSummary{Summary: summary, Warnings: warnings})
+	} else {
+		fmt.Fprintf(reportWriter, "%d linhas (%s -> %s), %d alteradas, %d anomalias, %d correções\n",
+			summary.Lines, summary.From, summary.To, summary.ChangedLines, summary.Anomalies, summary.Corrections)
+	}
+
+	if summary.Anomalies > 0 || summary.Corrections > 0 {
+		return exitAnomalies
+	}
+	return exitClean
+}
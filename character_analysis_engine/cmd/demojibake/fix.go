@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"demojibake/engine"
+)
+
+// runFix aplica as correções sugeridas. Sem -w o texto corrigido vai para a
+// saída padrão; com -w cada arquivo é reescrito no lugar, e com --transaction
+// todos são reescritos juntos ou nenhum é. --accept grava só as correções
+// escolhidas, pelos IDs mostrados em analyze. "-" lê da entrada padrão no modo
+// filtro (ver filter.go).
+func runFix(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var filter filterFlags
+	var inPlace, dryRun, transaction bool
+	var txDir, accept, analysis string
+	fs := newFlagSet("fix", "[flags] arquivo... | -", stderr)
+	flags.register(fs)
+	flags.registerWrite(fs)
+	filter.register(fs)
+	fs.BoolVar(&inPlace, "w", false, "reescreve os arquivos no lugar")
+	fs.BoolVar(&inPlace, "in-place", false, "o mesmo que -w")
+	fs.BoolVar(&dryRun, "dry-run", false, "apenas lista as correções, sem escrever nada")
+	fs.BoolVar(&transaction, "transaction", false, "reescreve todos os arquivos como uma unidade, desfazível com o comando undo")
+	fs.StringVar(&txDir, "tx-dir", defaultTransactionDir, "com --transaction, diretório dos originais guardados")
+	fs.StringVar(&accept, "accept", "", "grava só as correções com estes IDs, separados por vírgula")
+	fs.StringVar(&analysis, "analysis", "", "com --accept, relatório de analyze --format json em que os IDs foram escolhidos")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	if filter.requested(fs) {
+		if inPlace || dryRun || transaction || accept != "" || fs.NArg() > 1 {
+			return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "filter mode takes a single input and cannot be combined with -w, --dry-run, --transaction or --accept", nil))
+		}
+		return runFilter(&flags, &filter, fs, stdout, stderr)
+	}
+	if accept != "" && (transaction || dryRun || fs.NArg() > 1) {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "--accept takes a single file and cannot be combined with --transaction or --dry-run", nil))
+	}
+	if analysis != "" && accept == "" {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "--analysis requires --accept", nil))
+	}
+	if transaction && dryRun {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "--transaction cannot be combined with --dry-run", nil))
+	}
+	inPlace = inPlace || transaction
+	if fs.NArg() > 1 && !inPlace && !dryRun {
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "fixing several files requires -w or --dry-run", nil))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	options, warnings, err := flags.analysisOptions(e, fs)
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	if flags.format == formatText {
+		printWarnings(stderr, warnings)
+	}
+	if accept != "" {
+		return runFixSelected(e, fs.Arg(0), analysis, accept, options, flags.format, stdout, stderr)
+	}
+	if transaction {
+		return runFixTransaction(e, txDir, fs.Args(), options, flags.format, stdout, stderr)
+	}
+
+	exitCode := exitClean
+	var reports []*engine.Report
+	for _, path := range fs.Args() {
+		fixed, report, err := fixFile(e, path, options)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		report.Warnings = append(report.Warnings, warnings...)
+		reports = append(reports, report)
+		if code := exitCodeFor(report); code > exitCode {
+			exitCode = code
+		}
+
+		switch {
+		case dryRun:
+			if flags.format == formatText {
+				printReport(stdout, report)
+			}
+		case inPlace:
+			if len(report.SuggestedTransforms) == 0 {
+				continue
+			}
+			if err := e.WriteFixed(path, fixed, report, report.EffectiveOptions); err != nil {
+				return reportError(stderr, flags.format, err)
+			}
+			if flags.format == formatText {
+				fmt.Fprintf(stderr, "%s: %d correções aplicadas\n", path, len(report.SuggestedTransforms))
+			}
+		default:
+			io.WriteString(stdout, fixed)
+		}
+	}
+
+	// Em JSON os relatórios só vão para stdout quando stdout não recebe o texto
+	if flags.format == formatJSON && (dryRun || inPlace) {
+		writeJSON(stdout, reports)
+	} else if flags.format == formatJSON {
+		writeJSON(stderr, reports)
+	}
+	return exitCode
+}
+
+// runFixTransaction corrige os arquivos numa transação
+func runFixTransaction(e *engine.Engine, txDir string, paths []string, options engine.Options, format string, stdout, stderr io.Writer) int {
+	tx, err := e.ApplyTransaction(context.Background(), txDir, paths, options)
+	if err != nil {
+		return reportError(stderr, format, err)
+	}
+	if format == formatJSON {
+		writeJSON(stdout, tx)
+	} else {
+		for _, file := range tx.Files {
+			fmt.Fprintf(stderr, "%s: %d correções aplicadas\n", file.Path, file.Corrections)
+		}
+		for _, skip := range tx.Skipped {
+			fmt.Fprintf(stderr, "%s: não analisado (%s)\n", skip.Path, skip.Reason)
+		}
+		fmt.Fprintf(stderr, "transação %s: %d arquivos corrigidos, %d sem alterações (desfaça com: demojibake undo --tx-dir %s %s)\n",
+			tx.ID, len(tx.Files), len(tx.Unchanged), txDir, tx.ID)
+	}
+	if len(tx.Files) > 0 {
+		return exitAnomalies
+	}
+	return exitClean
+}
+
+// runFixSelected grava só as correções aceitas. Sem --analysis o arquivo é
+// analisado de novo; com ele, o arquivo precisa estar como na análise.
+func runFixSelected(e *engine.Engine, path, analysis, accepted string, options engine.Options, format string, stdout, stderr io.Writer) int {
+	report, err := loadAnalysis(e, path, analysis, options)
+	if err != nil {
+		return reportError(stderr, format, err)
+	}
+	result, err := e.ApplySelected(context.Background(), path, report, splitIDs(accepted))
+	if err != nil {
+		return reportError(stderr, format, err)
+	}
+	if format == formatJSON {
+		writeJSON(stdout, result)
+	} else {
+		for _, skipped := range result.NotApplied {
+			fmt.Fprintf(stderr, "%s: correção %s não aplicada (%s)\n", path, skipped.ID, skipped.Reason)
+		}
+		fmt.Fprintf(stderr, "%s: %d correções aplicadas, %d rejeitadas\n", path, len(result.Applied), result.Rejected)
+	}
+	switch {
+	case len(result.NotApplied) > 0:
+		return exitError
+	case result.Written:
+		return exitAnomalies
+	}
+	return exitClean
+}
+
+// loadAnalysis relatório salvo por analyze --format json ou, sem ele, uma
+// análise nova de path
+func loadAnalysis(e *engine.Engine, path, analysis string, options engine.Options) (*engine.Report, error) {
+	if analysis == "" {
+		return e.AnalyzeFile(context.Background(), path, options)
+	}
+	data, err := os.ReadFile(analysis)
+	if err != nil {
+		return nil, engine.NewError(engine.ErrCodeIO, "failed to read analysis", map[string]interface{}{"path": analysis, "cause": err.Error()})
+	}
+	var report *engine.Report
+	if err := json.Unmarshal(data, &report); err != nil || report == nil {
+		cause := "empty report"
+		if err != nil {
+			cause = err.Error()
+		}
+		return nil, engine.NewError(engine.ErrCodeInvalidArgument, "analysis must be a single report from analyze --format json", map[string]interface{}{"path": analysis, "cause": cause})
+	}
+	return report, nil
+}
+
+// splitIDs IDs de correções separados por vírgula
+func splitIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// fixFile lê e corrige um arquivo, sem gravá-lo, com as opções do projeto
+// em que ele está. Um arquivo ignorado pelo projeto volta sem alterações.
+func fixFile(e *engine.Engine, path string, options engine.Options) (string, *engine.Report, error) {
+	options, effective, err := e.OptionsForPath(path, options)
+	if err != nil {
+		return "", nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, engine.NewError(engine.ErrCodeIO, "failed to open document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	defer file.Close()
+
+	if effective != nil && effective.Ignored {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return "", nil, engine.NewError(engine.ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+		return string(data), effective.IgnoredReport(), nil
+	}
+	fixed, report, err := e.Fix(context.Background(), file, options)
+	if err != nil {
+		return "", nil, err
+	}
+	report.DocumentPath = path
+	if effective != nil {
+		report.Warnings = append(report.Warnings, effective.Warnings...)
+	}
+	return fixed, report, nil
+}
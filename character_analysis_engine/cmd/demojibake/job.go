@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"demojibake/engine"
+)
+
+// defaultJobDir diretório dos diários quando --job-dir é omitido
+const defaultJobDir = ".demojibake-jobs"
+
+// jobOutput saída de start e resume
+type jobOutput struct {
+	Job     engine.JobInfo `json:"job"`
+	Results []batchResult  `json:"results"`
+}
+
+// runJob lotes retomáveis: "start arquivo...", "resume ID", "rollback ID" ou "list"
+func runJob(args []string, stdout, stderr io.Writer) int {
+	var flags commonFlags
+	var jobDir, listFile string
+	var apply bool
+	fs := newFlagSet("job", "start [flags] arquivo... | resume [flags] ID | rollback ID | list", stderr)
+	flags.register(fs)
+	flags.registerWrite(fs)
+	fs.IntVar(&flags.workers, "workers", 0, "número de workers (0 usa o número de CPUs)")
+	fs.StringVar(&jobDir, "job-dir", defaultJobDir, "diretório dos diários e backups dos jobs")
+	fs.StringVar(&listFile, "list", "", "com start, arquivo com um caminho por linha (- para stdin)")
+	fs.BoolVar(&apply, "apply", false, "com start, grava as correções nos arquivos")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	// As flags também podem vir depois do subcomando
+	subcommand := fs.Arg(0)
+	if ok, code := parseFlags(fs, fs.Args()[1:]); !ok {
+		return code
+	}
+	if err := flags.validate(); err != nil {
+		return reportError(stderr, formatText, err)
+	}
+
+	switch subcommand {
+	case "list":
+		jobs, err := engine.ListJobs(jobDir)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, jobs)
+			return exitClean
+		}
+		for _, info := range jobs {
+			fmt.Fprintf(stdout, "%s: %s\n", info.ID, describeJob(info))
+		}
+		return exitClean
+
+	case "rollback":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return exitError
+		}
+		audit, err := flags.openAuditLog()
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		job, err := engine.OpenJob(jobDir, fs.Arg(0))
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		defer job.Close()
+		info, err := job.Rollback(audit)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatJSON {
+			writeJSON(stdout, info)
+			return exitClean
+		}
+		printPendingFixes(stdout, info)
+		fmt.Fprintf(stdout, "%s: %s\n", info.ID, describeJob(info))
+		return exitClean
+
+	case "start", "resume":
+	default:
+		return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidArgument, "unknown job subcommand", map[string]interface{}{"subcommand": subcommand}))
+	}
+
+	e, err := flags.newEngine()
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	defer e.Shutdown(engine.DefaultShutdownTimeout)
+
+	var job *engine.Job
+	if subcommand == "start" {
+		paths := fs.Args()
+		if listFile != "" {
+			listed, err := readPathList(listFile)
+			if err != nil {
+				return reportError(stderr, flags.format, err)
+			}
+			paths = append(paths, listed...)
+		}
+		if len(paths) == 0 {
+			fs.Usage()
+			return exitError
+		}
+		// O diário guarda caminhos absolutos: resume pode rodar de outro diretório
+		for i, path := range paths {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return reportError(stderr, flags.format, engine.NewError(engine.ErrCodeInvalidPath, "failed to resolve document path", map[string]interface{}{"path": path, "cause": err.Error()}))
+			}
+			paths[i] = absPath
+		}
+		options, warnings, err := flags.analysisOptions(e, fs)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatText {
+			printWarnings(stderr, warnings)
+		}
+		job, err = engine.CreateJob(jobDir, paths, options, apply)
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+		if flags.format == formatText {
+			fmt.Fprintf(stderr, "job %s\n", job.ID())
+		}
+	} else {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return exitError
+		}
+		job, err = engine.OpenJob(jobDir, fs.Arg(0))
+		if err != nil {
+			return reportError(stderr, flags.format, err)
+		}
+	}
+	defer job.Close()
+
+	// Ctrl-C interrompe o lote; o job continua retomável
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var resultsLock sync.Mutex
+	var results []batchResult
+	info, err := e.RunJob(ctx, job, func(path string, report *engine.Report, err error) {
+		result := batchResult{Path: path}
+		if err != nil {
+			result.Error = engine.AsError(err, engine.ErrCodeInternal)
+		} else {
+			result.Status = report.Status
+			result.SkipReason = report.SkipReason
+			result.Encoding = report.SourceCharacterSet
+			result.Anomalies = report.AnomalyCount
+			result.Corrections = len(report.SuggestedTransforms)
+			result.FromCache = report.FromCache
+		}
+		resultsLock.Lock()
+		results = append(results, result)
+		resultsLock.Unlock()
+	})
+	if err != nil {
+		return reportError(stderr, flags.format, err)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if flags.format == formatJSON {
+		writeJSON(stdout, jobOutput{Job: info, Results: results})
+	} else {
+		printPendingFixes(stdout, info)
+		for _, result := range results {
+			switch {
+			case result.Error != nil:
+				fmt.Fprintf(stdout, "%s: erro: %s\n", result.Path, result.Error.Message)
+			case result.Status == engine.StatusSkipped:
+				fmt.Fprintf(stdout, "%s: não analisado (%s)\n", result.Path, result.SkipReason)
+			default:
+				fmt.Fprintf(stdout, "%s: %s, %d anomalias, %d correções\n", result.Path, result.Encoding, result.Anomalies, result.Corrections)
+			}
+		}
+		fmt.Fprintf(stdout, "\n%s: %s\n", info.ID, describeJob(info))
+		if info.State == engine.JobRunning {
+			fmt.Fprintf(stdout, "interrompido; continue com: demojibake job --job-dir %s resume %s\n", jobDir, info.ID)
+		}
+	}
+
+	if info.Failed > 0 || info.State == engine.JobRunning {
+		return exitError
+	}
+	for _, result := range results {
+		if result.Anomalies > 0 || result.Corrections > 0 {
+			return exitAnomalies
+		}
+	}
+	return exitClean
+}
+
+// describeJob linha de progresso de um job
+func describeJob(info engine.JobInfo) string {
+	mode := "análise"
+	if info.Apply {
+		mode = "correção"
+	}
+	return fmt.Sprintf("%s, %s, %d/%d concluídos, %d com erro", info.State, mode, info.Completed, info.Total, info.Failed)
+}
+
+// printPendingFixes mostra o destino das correções interrompidas por uma queda
+func printPendingFixes(w io.Writer, info engine.JobInfo) {
+	for _, fix := range info.PendingFixes {
+		if fix.Resolution == "" {
+			fmt.Fprintf(w, "%s: correção interrompida (%s)\n", fix.Path, fix.State)
+			continue
+		}
+		fmt.Fprintf(w, "%s: correção interrompida (%s): %s\n", fix.Path, fix.State, fix.Resolution)
+	}
+}
@@ -0,0 +1,114 @@
+// Command demojibake analisa e corrige problemas de codificação (mojibake)
+// em arquivos de texto, usando o mesmo motor da biblioteca nativa.
+//
+// Uso:
+//
+//	demojibake <comando> [flags] [arquivos...]
+//
+// Comandos: analyze, fix, diff, detect, batch, job, undo, backups, audit,
+// feedback, config, dict, version.
+//
+// Códigos de saída: 0 nenhum problema encontrado, 1 anomalias encontradas,
+// 2 erro de uso ou de processamento.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"demojibake/engine"
+)
+
+// Códigos de saída
+const (
+	exitClean     = 0
+	exitAnomalies = 1
+	exitError     = 2
+)
+
+// command subcomando da CLI
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"analyze", "relatório completo de anomalias e correções sugeridas", runAnalyze},
+		{"fix", "aplica as correções sugeridas", runFix},
+		{"diff", "mostra as correções como diff unificado ou patch", runDiff},
+		{"detect", "detecta o encoding e conta anomalias, sem sugerir correções", runDetect},
+		{"batch", "analisa muitos arquivos em paralelo", runBatch},
+		{"job", "lotes retomáveis com diário de progresso", runJob},
+		{"undo", "desfaz uma transação de fix --transaction", runUndo},
+		{"backups", "lista, restaura e poda os originais guardados", runBackups},
+		{"audit", "confere os arquivos contra o log de auditoria", runAudit},
+		{"feedback", "aprende com as correções aceitas e rejeitadas", runFeedback},
+		{"config", "mostra a configuração de projeto que vale para cada arquivo", runConfig},
+		{"dict", "consulta o dicionário linguístico", runDict},
+		{"version", "mostra a versão", runVersion},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return exitError
+		}
+		return exitClean
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "demojibake: comando desconhecido %q\n\n", args[0])
+	printUsage(stderr)
+	return exitError
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Uso: demojibake <comando> [flags] [arquivos...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Comandos:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use \"demojibake <comando> -h\" para ver as flags de cada comando.")
+	fmt.Fprintln(w, "Saída: 0 sem anomalias, 1 anomalias encontradas, 2 erro.")
+}
+
+func runVersion(args []string, stdout, stderr io.Writer) int {
+	fmt.Fprintf(stdout, "demojibake %s\n", engine.Version)
+	return exitClean
+}
+
+// reportError imprime o erro no formato pedido e devolve exitError
+func reportError(stderr io.Writer, format string, err error) int {
+	engineErr := engine.AsError(err, engine.ErrCodeInternal)
+	if format == formatJSON {
+		writeJSON(stderr, map[string]interface{}{"error": engineErr})
+		return exitError
+	}
+	fmt.Fprintf(stderr, "demojibake: %s\n", engineErr.Message)
+	keys := make([]string, 0, len(engineErr.Details))
+	for key := range engineErr.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(stderr, "  %s: %v\n", key, engineErr.Details[key])
+	}
+	return exitError
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"demojibake/engine"
+)
+
+// Formatos de saída
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// commonFlags flags compartilhadas pelos comandos de análise
+type commonFlags struct {
+	format       string
+	options      string
+	aggressive   bool
+	threshold    float64
+	corpus       string
+	vocabulary   string
+	cacheDir     string
+	cacheMax     int64
+	backupDir    string
+	auditLog     string
+	feedback     string
+	noProject    bool
+	workers      int
+	timeout      time.Duration
+	maxAnomalies int
+}
+
+// register registra as flags comuns no FlagSet
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.format, "format", formatText, "formato de saída: text ou json")
+	fs.StringVar(&c.options, "options", "", "opções de análise em JSON")
+	fs.BoolVar(&c.aggressive, "aggressive", false, "habilita o modo agressivo (baixa em 0.2 o limiar de confiança)")
+	fs.Float64Var(&c.threshold, "threshold", -1, "confiança mínima das correções (0 a 1)")
+	fs.StringVar(&c.corpus, "corpus", "", "arquivo de corpus no formato binário do motor")
+	fs.StringVar(&c.vocabulary, "vocabulary", "", "arquivo de vocabulário, uma palavra por linha")
+	fs.StringVar(&c.cacheDir, "cache-dir", "", "guarda os relatórios neste diretório e reaproveita os de arquivos inalterados")
+	fs.Int64Var(&c.cacheMax, "cache-max-bytes", 0, "tamanho máximo de --cache-dir; as entradas usadas há mais tempo são apagadas (padrão do motor se omitido)")
+	fs.DurationVar(&c.timeout, "timeout", 0, "tempo máximo de análise por arquivo, ex. 30s (padrão do motor se omitido)")
+	fs.IntVar(&c.maxAnomalies, "max-anomalies", 0, "anomalias registradas por arquivo (padrão do motor se omitido)")
+	fs.StringVar(&c.feedback, "feedback", "", "aplica as decisões de revisores guardadas neste arquivo às pontuações (o comando feedback usa "+defaultFeedbackFile()+" se omitido)")
+	fs.BoolVar(&c.noProject, "no-project-config", false, "não procura "+engine.ProjectConfigFile+" a partir dos arquivos")
+}
+
+// registerWrite registra --backup-dir e --audit-log nos comandos que gravam arquivos
+func (c *commonFlags) registerWrite(fs *flag.FlagSet) {
+	fs.StringVar(&c.backupDir, "backup-dir", defaultBackupDir(), "repositório dos originais substituídos (vazio exige backup_files false em --options)")
+	fs.StringVar(&c.auditLog, "audit-log", "", "registra cada arquivo reescrito neste log JSONL")
+}
+
+// openAuditLog log de auditoria pedido em --audit-log, ou nil
+func (c *commonFlags) openAuditLog() (*engine.AuditLog, error) {
+	if c.auditLog == "" {
+		return nil, nil
+	}
+	return engine.OpenAuditLog(c.auditLog)
+}
+
+// defaultBackupDir repositório padrão no diretório de cache do usuário
+func defaultBackupDir() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "demojibake", "backups")
+}
+
+// defaultFeedbackFile arquivo do comando feedback quando --feedback é
+// omitido. Os demais comandos só usam o aprendido se --feedback for dado.
+func defaultFeedbackFile() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "demojibake", "feedback.json")
+}
+
+// validate verifica o formato pedido
+func (c *commonFlags) validate() error {
+	if c.format != formatText && c.format != formatJSON {
+		return engine.NewError(engine.ErrCodeInvalidArgument, "unknown output format", map[string]interface{}{"format": c.format})
+	}
+	return nil
+}
+
+// newEngine cria a instância do motor a partir das flags
+func (c *commonFlags) newEngine() (*engine.Engine, error) {
+	config := engine.Config{Workers: c.workers}
+	config.ResultCache.Dir = c.cacheDir
+	config.ResultCache.DiskMaxBytes = c.cacheMax
+	config.Backups.Dir = c.backupDir
+	config.AuditLog = c.auditLog
+	config.FeedbackFile = c.feedback
+	config.ProjectConfig = !c.noProject
+	if c.corpus != "" {
+		data, err := os.ReadFile(c.corpus)
+		if err != nil {
+			return nil, engine.NewError(engine.ErrCodeIO, "failed to read corpus", map[string]interface{}{"path": c.corpus, "cause": err.Error()})
+		}
+		config.Corpus = data
+	}
+	if c.vocabulary != "" {
+		data, err := os.ReadFile(c.vocabulary)
+		if err != nil {
+			return nil, engine.NewError(engine.ErrCodeIO, "failed to read vocabulary", map[string]interface{}{"path": c.vocabulary, "cause": err.Error()})
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if word := strings.TrimSpace(line); word != "" {
+				config.Vocabulary = append(config.Vocabulary, word)
+			}
+		}
+	}
+	return engine.New(config)
+}
+
+// analysisOptions combina as opções padrão do motor com --options e as
+// flags de atalho, que têm precedência. Ambas passam por ParseOptions, então
+// também prevalecem sobre os .demojibake.json dos projetos.
+func (c *commonFlags) analysisOptions(e *engine.Engine, fs *flag.FlagSet) (engine.Options, []engine.Warning, error) {
+	options := e.DefaultOptions()
+	var warnings []engine.Warning
+	if c.options != "" {
+		parsed, parseWarnings, err := engine.ParseOptions(c.options, options)
+		if err != nil {
+			return options, nil, err
+		}
+		options, warnings = parsed, parseWarnings
+	}
+
+	shortcuts := make(map[string]interface{})
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "aggressive":
+			shortcuts["aggressive_mode"] = c.aggressive
+		case "threshold":
+			shortcuts["confidence_threshold"] = c.threshold
+		case "timeout":
+			shortcuts["timeout_ms"] = c.timeout.Milliseconds()
+		case "max-anomalies":
+			shortcuts["max_anomalies"] = c.maxAnomalies
+		}
+	})
+	if len(shortcuts) == 0 {
+		return options, warnings, nil
+	}
+	if _, ok := shortcuts["confidence_threshold"]; ok && (c.threshold < 0 || c.threshold > 1) {
+		return options, nil, engine.NewError(engine.ErrCodeInvalidOptions, "confidence_threshold must be between 0 and 1", map[string]interface{}{"confidence_threshold": c.threshold})
+	}
+	if c.timeout < 0 || c.maxAnomalies < 0 {
+		return options, nil, engine.NewError(engine.ErrCodeInvalidOptions, "timeout and max-anomalies must not be negative", nil)
+	}
+	data, _ := json.Marshal(shortcuts)
+	options, _, err := engine.ParseOptions(string(data), options)
+	if err != nil {
+		return options, nil, engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)
+	}
+	return options, warnings, nil
+}
+
+// writeJSON serializa v com indentação
+func writeJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+// writeJSONLine serializa v em uma única linha (JSON Lines)
+func writeJSONLine(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+// printWarnings imprime avisos de opções no stderr (modo texto)
+func printWarnings(stderr io.Writer, warnings []engine.Warning) {
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "aviso: %s\n", w.Message)
+	}
+}
+
+// anomalyCounts conta anomalias por categoria
+func anomalyCounts(report *engine.Report) map[string]int {
+	counts := make(map[string]int)
+	for _, anomaly := range report.EncodingAnomalies {
+		counts[anomaly.AnomalyCategory]++
+	}
+	return counts
+}
+
+// describeSkip descreve por que um documento não foi analisado
+func describeSkip(reason string, class *engine.ContentClassification) string {
+	if class != nil && class.Format != "" {
+		return reason + ", " + class.Format
+	}
+	if class != nil {
+		return reason + ", " + class.Reason
+	}
+	return reason
+}
+
+// exitCodeFor retorna exitAnomalies se o relatório tem algo a corrigir
+func exitCodeFor(report *engine.Report) int {
+	if len(report.EncodingAnomalies) > 0 || len(report.SuggestedTransforms) > 0 {
+		return exitAnomalies
+	}
+	return exitClean
+}
+
+// parseFlags processa os argumentos e trata -h e erros de uso
+func parseFlags(fs *flag.FlagSet, args []string) (bool, int) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return false, exitClean
+		}
+		return false, exitError
+	}
+	return true, 0
+}
+
+// newFlagSet cria um FlagSet com a mensagem de uso do comando
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Uso: demojibake %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"demojibake/engine"
+)
+
+// defaultTransactionDir diretório das transações quando --tx-dir é omitido
+const defaultTransactionDir = ".demojibake-transactions"
+
+// runUndo desfaz uma transação de fix --transaction: "ID" ou "list"
+func runUndo(args []string, stdout, stderr io.Writer) int {
+	var format, txDir, auditLog string
+	fs := newFlagSet("undo", "[flags] ID | list", stderr)
+	fs.StringVar(&format, "format", formatText, "formato de saída: text ou json")
+	fs.StringVar(&txDir, "tx-dir", defaultTransactionDir, "diretório das transações")
+	fs.StringVar(&auditLog, "audit-log", "", "registra cada arquivo restaurado neste log JSONL")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if format != formatText && format != formatJSON {
+		return reportError(stderr, formatText, engine.NewError(engine.ErrCodeInvalidArgument, "unknown output format", map[string]interface{}{"format": format}))
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	if fs.Arg(0) == "list" {
+		transactions, err := engine.ListTransactions(txDir)
+		if err != nil {
+			return reportError(stderr, format, err)
+		}
+		if format == formatJSON {
+			writeJSON(stdout, transactions)
+			return exitClean
+		}
+		for _, tx := range transactions {
+			fmt.Fprintf(stdout, "%s: %s, %d arquivos\n", tx.ID, tx.State, len(tx.Files))
+		}
+		return exitClean
+	}
+
+	var audit *engine.AuditLog
+	if auditLog != "" {
+		opened, err := engine.OpenAuditLog(auditLog)
+		if err != nil {
+			return reportError(stderr, format, err)
+		}
+		audit = opened
+	}
+	tx, err := engine.UndoTransaction(txDir, fs.Arg(0), audit)
+	if err != nil {
+		return reportError(stderr, format, err)
+	}
+	if format == formatJSON {
+		writeJSON(stdout, tx)
+		return exitClean
+	}
+	restored := 0
+	for _, file := range tx.Files {
+		if file.Resolution == engine.FixResolutionRolledBack {
+			fmt.Fprintf(stdout, "%s: restaurado\n", file.Path)
+			restored++
+		}
+	}
+	fmt.Fprintf(stdout, "transação %s desfeita: %d arquivos restaurados\n", tx.ID, restored)
+	return exitClean
+}
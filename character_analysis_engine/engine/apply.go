@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ApplyTransformations aplica ao conteúdo uma lista de edições sem
+// sobreposição. Cada edição precisa apontar para o trecho exato do
+// documento; a lista produzida pelo resolvedor já atende a essa condição.
+func ApplyTransformations(content string, transformations []TextTransformation) (string, error) {
+	edits := make([]TextTransformation, len(transformations))
+	copy(edits, transformations)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].DocumentPosition < edits[j].DocumentPosition
+	})
+
+	var builder strings.Builder
+	builder.Grow(len(content))
+	cursor := 0
+	for _, edit := range edits {
+		end := transformationEnd(edit)
+		if edit.DocumentPosition < cursor || end > len(content) || content[edit.DocumentPosition:end] != edit.OriginalSequence {
+			return "", NewError(ErrCodeInvalidArgument, "transformation does not match the document", map[string]interface{}{
+				"documentPosition": edit.DocumentPosition,
+				"originalSequence": edit.OriginalSequence,
+			})
+		}
+		builder.WriteString(content[cursor:edit.DocumentPosition])
+		builder.WriteString(edit.TransformedSequence)
+		cursor = end
+	}
+	builder.WriteString(content[cursor:])
+	return builder.String(), nil
+}
+
+// Fix analisa o conteúdo de r e devolve o texto em UTF-8 com as correções
+// sugeridas já aplicadas, junto com o relatório que as originou
+func (e *Engine) Fix(ctx context.Context, r io.Reader, options Options) (string, *Report, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+
+	data, tooLarge, readErr := readLimited(r, options)
+	if readErr != nil {
+		return "", nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"cause": readErr.Error()})
+	}
+	if tooLarge {
+		return "", nil, NewError(ErrCodeLimitExceeded, fileSizeWarning(options).Message, map[string]interface{}{"limit": "max_file_size"})
+	}
+	return e.fixContent(ctx, data, options)
+}
+
+// fixContent corrige o documento já lido; o chamador mantém a instância adquirida
+func (e *Engine) fixContent(ctx context.Context, data []byte, options Options) (string, *Report, error) {
+	_, fixed, report, err := e.fixDocument(ctx, data, options)
+	return fixed, report, err
+}
+
+// fixDocument como fixContent, devolvendo também o texto decodificado ao
+// qual as posições do relatório se referem
+func (e *Engine) fixDocument(ctx context.Context, data []byte, options Options) (string, string, *Report, error) {
+	// Binários saem inalterados
+	class := classifySample(data)
+	if class.Class == ContentBinary {
+		return string(data), string(data), skippedReport("", class, options), nil
+	}
+	ctx, cancel := withAnalysisTimeout(ctx, options)
+	defer cancel()
+
+	content, encoding := decodeDocument(data)
+	report, analyzeErr := e.analyzeText(ctx, "", content, encoding, options)
+	if analyzeErr != nil {
+		if timedOut(ctx) {
+			return "", "", nil, NewError(ErrCodeLimitExceeded, timeoutWarning(options).Message, map[string]interface{}{"limit": "timeout_ms"})
+		}
+		return "", "", nil, analyzeErr
+	}
+	report.ContentClass = &class
+	fixed, applyErr := ApplyTransformations(content, report.SuggestedTransforms)
+	if applyErr != nil {
+		return "", "", nil, applyErr
+	}
+	return content, fixed, report, nil
+}
+
+// WriteFixed grava no lugar o conteúdo corrigido segundo report. Com
+// backup_files, o original vai antes para o repositório de backups da
+// instância (ver backup.go); sem repositório configurado a escrita é
+// recusada. A escrita é registrada no log de auditoria. Um documento que
+// mudou desde a análise não é tocado (stale_analysis).
+func (e *Engine) WriteFixed(path, content string, report *Report, options Options) error {
+	return e.applyFixed(AuditFix, path, report.ContentSHA256, content, report.SuggestedTransforms, options)
+}
+
+// applyFixed grava a correção e a registra como operation. Com expected, o
+// SHA-256 do conteúdo a partir do qual content foi calculado, a escrita é
+// recusada se o documento mudou desde essa leitura.
+func (e *Engine) applyFixed(operation, path, expected, content string, transformations []TextTransformation, options Options) error {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	if current := hashContent(original); expected != "" && current != expected {
+		return NewError(ErrCodeStaleAnalysis, "document changed since it was read", map[string]interface{}{
+			"path":     path,
+			"expected": expected,
+			"actual":   current,
+		})
+	}
+	if options.BackupFiles {
+		if e.backups == nil {
+			return NewError(ErrCodeInvalidOptions, "backup_files requires a backup store; configure backups.dir or set backup_files to false", map[string]interface{}{"path": path})
+		}
+		if _, err := e.backups.Save(path, original); err != nil {
+			return err
+		}
+	}
+	return writeRecorded(e.audit, operation, path, original, content, transformations, &options, e.currentDictionaryDigest())
+}
+
+// writeRecorded grava content em path e registra a escrita em audit antes da
+// troca: o documento nunca muda sem registro. Se a troca falhar depois do
+// registro, um registro aborted informa que o documento ficou com pre.
+func writeRecorded(audit *AuditLog, operation, path string, pre []byte, content string, transformations []TextTransformation, options *Options, dictionaryVersion string) error {
+	tmp, err := stageFixed(path, content)
+	if err != nil {
+		return err
+	}
+	if err := audit.record(operation, path, pre, []byte(content), transformations, options, dictionaryVersion); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		replaceErr := NewError(ErrCodeIO, "failed to replace document", map[string]interface{}{"path": path, "cause": err.Error()})
+		if auditErr := audit.record(AuditAborted, path, pre, pre, nil, nil, ""); auditErr != nil {
+			replaceErr.Details["audit"] = auditErr.Error()
+		}
+		return replaceErr
+	}
+	return nil
+}
+
+// stageFixed grava o conteúdo num temporário ao lado de path, com as
+// permissões do documento; um documento removido é recriado com 0644
+func stageFixed(path, content string) (string, error) {
+	perm := os.FileMode(0o644)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", NewError(ErrCodeIO, "failed to create document directory", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+	default:
+		return "", NewError(ErrCodeIO, "failed to stat document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+
+	tmp := path + ".demojibake.tmp"
+	if err := os.WriteFile(tmp, []byte(content), perm); err != nil {
+		os.Remove(tmp)
+		return "", NewError(ErrCodeIO, "failed to write document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	return tmp, nil
+}
@@ -0,0 +1,328 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operações registradas no log de auditoria
+const (
+	AuditFix         = "fix"
+	AuditJob         = "job"
+	AuditTransaction = "transaction"
+	AuditRollback    = "rollback"
+	AuditUndo        = "undo"
+	AuditRestore     = "restore"
+	AuditSelect      = "select"
+	// AuditAborted a escrita registrada logo antes não aconteceu: o
+	// documento continua com o conteúdo de preSha256
+	AuditAborted = "aborted"
+)
+
+// Situação de um arquivo na verificação do log
+const (
+	AuditMatched  = "matched"
+	AuditModified = "modified"
+	AuditMissing  = "missing"
+)
+
+// maxAuditLine maior linha aceita ao ler o log; registros com muitas
+// transformações passam facilmente de 64 KiB
+const maxAuditLine = 64 << 20
+
+// AuditRecord uma escrita em arquivo. Previous é o SHA-256 da linha anterior
+// do log: apagar ou alterar um registro quebra a cadeia a partir dele.
+type AuditRecord struct {
+	Time              time.Time            `json:"time"`
+	Operation         string               `json:"operation"`
+	Path              string               `json:"path"`
+	PreSHA256         string               `json:"preSha256"`
+	PostSHA256        string               `json:"postSha256"`
+	EngineVersion     string               `json:"engineVersion"`
+	DictionaryVersion string               `json:"dictionaryVersion,omitempty"`
+	Options           *Options             `json:"options,omitempty"`
+	Transformations   []TextTransformation `json:"transformations"`
+	Previous          string               `json:"previous"`
+}
+
+// AuditLog log JSONL só de acréscimos. Cada registro é sincronizado antes
+// de Append retornar. Vários processos podem gravar o mesmo log: Append
+// segura o arquivo <log>.lock e lê o que os outros acrescentaram antes de
+// continuar a cadeia.
+type AuditLog struct {
+	path string
+
+	lock    sync.Mutex
+	last    string
+	newline bool
+	// size bytes do log já lidos ou gravados por esta instância
+	size int64
+}
+
+// OpenAuditLog abre o log em path, criando-o se preciso, e lê o último
+// registro para continuar a cadeia
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if path == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "audit log not configured", nil)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, NewError(ErrCodeInvalidPath, "failed to resolve audit log path", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to create audit log directory", map[string]interface{}{"path": absPath, "cause": err.Error()})
+	}
+	log := &AuditLog{path: absPath}
+	if err := log.readFrom(0); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// readFrom lê os registros a partir de offset e atualiza o fim da cadeia.
+// Uma última linha sem \n (queda durante a escrita) não é um registro.
+func (l *AuditLog) readFrom(offset int64) error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.last, l.newline, l.size = "", false, 0
+		return nil
+	}
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to open audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return NewError(ErrCodeIO, "failed to read audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+
+	size := offset
+	l.newline = false
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		size += int64(len(line))
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && json.Valid(trimmed) {
+				l.last = hashContent(trimmed)
+			}
+		} else if len(line) > 0 {
+			l.newline = true
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return NewError(ErrCodeIO, "failed to read audit log", map[string]interface{}{"path": l.path, "cause": readErr.Error()})
+		}
+	}
+	l.size = size
+	return nil
+}
+
+// refresh lê o que outros processos acrescentaram desde a última leitura.
+// Um log menor que o conhecido foi substituído e é relido do início.
+func (l *AuditLog) refresh() error {
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.last, l.newline, l.size = "", false, 0
+		return nil
+	}
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to stat audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	switch {
+	case info.Size() == l.size:
+		return nil
+	case info.Size() < l.size:
+		l.last = ""
+		return l.readFrom(0)
+	default:
+		return l.readFrom(l.size)
+	}
+}
+
+// Path arquivo do log
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+// Append grava o registro; um log nil não grava nada
+func (l *AuditLog) Append(record AuditRecord) error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to lock audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	defer unlock()
+	if err := l.refresh(); err != nil {
+		return err
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	if record.EngineVersion == "" {
+		record.EngineVersion = Version
+	}
+	if record.Transformations == nil {
+		record.Transformations = []TextTransformation{}
+	}
+	record.Previous = l.last
+	line, err := json.Marshal(record)
+	if err != nil {
+		return NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to open audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	defer file.Close()
+	data := append(line, '\n')
+	if l.newline {
+		data = append([]byte{'\n'}, data...)
+	}
+	if _, err := file.Write(data); err != nil {
+		return NewError(ErrCodeIO, "failed to write audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	if err := file.Sync(); err != nil {
+		return NewError(ErrCodeIO, "failed to sync audit log", map[string]interface{}{"path": l.path, "cause": err.Error()})
+	}
+	l.last = hashContent(line)
+	l.newline = false
+	l.size += int64(len(data))
+	return nil
+}
+
+// record registra uma escrita de path de pre para post
+func (l *AuditLog) record(operation, path string, pre, post []byte, transformations []TextTransformation, options *Options, dictionaryVersion string) error {
+	if l == nil {
+		return nil
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	return l.Append(AuditRecord{
+		Operation:         operation,
+		Path:              path,
+		PreSHA256:         hashContent(pre),
+		PostSHA256:        hashContent(post),
+		DictionaryVersion: dictionaryVersion,
+		Options:           options,
+		Transformations:   transformations,
+	})
+}
+
+// AuditFileStatus estado atual de um arquivo frente ao último registro dele
+type AuditFileStatus struct {
+	Path     string    `json:"path"`
+	Status   string    `json:"status"`
+	Expected string    `json:"expected"`
+	Actual   string    `json:"actual,omitempty"`
+	Recorded time.Time `json:"recorded"`
+}
+
+// AuditVerification resultado de VerifyAuditLog. BrokenAt lista as linhas
+// (a partir de 1) que não são JSON válido ou cujo Previous não confere.
+type AuditVerification struct {
+	Path      string            `json:"path"`
+	Records   int               `json:"records"`
+	ChainOK   bool              `json:"chainOk"`
+	BrokenAt  []int             `json:"brokenAt,omitempty"`
+	Matched   int               `json:"matched"`
+	Modified  int               `json:"modified"`
+	Missing   int               `json:"missing"`
+	Files     []AuditFileStatus `json:"files"`
+	Verified  time.Time         `json:"verified"`
+	LastEntry time.Time         `json:"lastEntry,omitempty"`
+}
+
+// OK informa se a cadeia está íntegra e todos os arquivos conferem
+func (v *AuditVerification) OK() bool {
+	return v.ChainOK && v.Modified == 0 && v.Missing == 0
+}
+
+// VerifyAuditLog confere o log de auditoria configurado na instância
+func (e *Engine) VerifyAuditLog() (*AuditVerification, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.audit == nil {
+		return nil, NewError(ErrCodeInvalidArgument, "audit log not configured", nil)
+	}
+	return VerifyAuditLog(e.audit.path)
+}
+
+// VerifyAuditLog confere a cadeia do log e recalcula o SHA-256 de cada
+// arquivo registrado, comparando com o postSha256 do último registro dele
+func VerifyAuditLog(path string) (*AuditVerification, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to open audit log", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	defer file.Close()
+
+	result := &AuditVerification{Path: path, ChainOK: true, Files: []AuditFileStatus{}, Verified: time.Now().UTC()}
+	latest := map[string]AuditRecord{}
+	previous := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLine)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			result.ChainOK = false
+			result.BrokenAt = append(result.BrokenAt, lineNumber)
+			continue
+		}
+		if record.Previous != previous {
+			result.ChainOK = false
+			result.BrokenAt = append(result.BrokenAt, lineNumber)
+		}
+		previous = hashContent(line)
+		result.Records++
+		result.LastEntry = record.Time
+		latest[record.Path] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read audit log", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+
+	for filePath, record := range latest {
+		status := AuditFileStatus{Path: filePath, Expected: record.PostSHA256, Recorded: record.Time}
+		data, err := os.ReadFile(filePath)
+		switch {
+		case err != nil:
+			status.Status = AuditMissing
+			result.Missing++
+		case hashContent(data) == record.PostSHA256:
+			status.Status = AuditMatched
+			status.Actual = record.PostSHA256
+			result.Matched++
+		default:
+			status.Status = AuditModified
+			status.Actual = hashContent(data)
+			result.Modified++
+		}
+		result.Files = append(result.Files, status)
+	}
+	sort.Slice(result.Files, func(a, b int) bool { return result.Files[a].Path < result.Files[b].Path })
+	return result, nil
+}
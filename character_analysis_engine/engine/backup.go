@@ -0,0 +1,395 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Arquivos do repositório de backups
+const (
+	backupCatalog = "catalog.jsonl"
+	backupObjects = "objects"
+	backupLock    = "lock"
+)
+
+// backupObjectGrace idade mínima de um objeto sem entrada no catálogo para
+// que Prune o apague: um Save de outro processo pode estar entre gravar o
+// objeto e a linha do catálogo
+const backupObjectGrace = time.Hour
+
+// BackupStoreConfig repositório de backups dos originais. Com backup_files o
+// original de cada arquivo corrigido é guardado aqui; sem Dir, escritas com
+// backup_files são recusadas. O conteúdo é armazenado pelo hash: versões
+// repetidas ocupam espaço uma única vez.
+type BackupStoreConfig struct {
+	// Dir diretório do repositório; vazio desativa
+	Dir string `json:"dir"`
+
+	// MaxAgeDays descarta backups mais antigos que isso; 0 sem limite
+	MaxAgeDays int `json:"maxAgeDays"`
+
+	// MaxBytes tamanho total dos originais guardados; acima dele os backups
+	// mais antigos são descartados. 0 sem limite.
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// BackupEntry versão guardada de um arquivo
+type BackupEntry struct {
+	Path string    `json:"path"`
+	Hash string    `json:"hash"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"`
+}
+
+// PruneResult efeito da política de retenção
+type PruneResult struct {
+	RemovedEntries int   `json:"removedEntries"`
+	RemovedObjects int   `json:"removedObjects"`
+	FreedBytes     int64 `json:"freedBytes"`
+	Entries        int   `json:"entries"`
+	Bytes          int64 `json:"bytes"`
+}
+
+// BackupStore repositório em disco: objects/xx/<hash> com os conteúdos e
+// catalog.jsonl com uma linha por backup. O catálogo só recebe acréscimos,
+// exceto em Prune, que o regrava. Save e Prune seguram o arquivo lock do
+// repositório, que vários processos podem compartilhar.
+type BackupStore struct {
+	dir      string
+	maxAge   time.Duration
+	maxBytes int64
+
+	lock sync.Mutex
+}
+
+// OpenBackupStore abre o repositório, criando o diretório se preciso
+func OpenBackupStore(config BackupStoreConfig) (*BackupStore, error) {
+	if config.Dir == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "backup directory not configured", nil)
+	}
+	if config.MaxAgeDays < 0 || config.MaxBytes < 0 {
+		return nil, NewError(ErrCodeInvalidArgument, "backup retention limits must not be negative", map[string]interface{}{"maxAgeDays": config.MaxAgeDays, "maxBytes": config.MaxBytes})
+	}
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, NewError(ErrCodeInvalidPath, "failed to resolve backup directory", map[string]interface{}{"dir": config.Dir, "cause": err.Error()})
+	}
+	if err := os.MkdirAll(filepath.Join(dir, backupObjects), 0o755); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to create backup directory", map[string]interface{}{"dir": dir, "cause": err.Error()})
+	}
+	store := &BackupStore{
+		dir:      dir,
+		maxAge:   time.Duration(config.MaxAgeDays) * 24 * time.Hour,
+		maxBytes: config.MaxBytes,
+	}
+	return store, nil
+}
+
+// Dir diretório do repositório
+func (s *BackupStore) Dir() string {
+	return s.dir
+}
+
+// HasRetention informa se há limite de idade ou de tamanho configurado
+func (s *BackupStore) HasRetention() bool {
+	return s.maxAge > 0 || s.maxBytes > 0
+}
+
+// acquire trava o repositório na instância e entre processos
+func (s *BackupStore) acquire() (func(), error) {
+	s.lock.Lock()
+	unlock, err := lockFile(filepath.Join(s.dir, backupLock))
+	if err != nil {
+		s.lock.Unlock()
+		return nil, NewError(ErrCodeIO, "failed to lock backup directory", map[string]interface{}{"dir": s.dir, "cause": err.Error()})
+	}
+	return func() {
+		unlock()
+		s.lock.Unlock()
+	}, nil
+}
+
+// Save guarda o conteúdo original de path. O objeto é gravado antes da linha
+// do catálogo, então o catálogo nunca aponta para um conteúdo inexistente.
+func (s *BackupStore) Save(path string, data []byte) (BackupEntry, error) {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	entry := BackupEntry{Path: path, Hash: hashContent(data), Size: int64(len(data)), Time: time.Now().UTC()}
+
+	release, err := s.acquire()
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	defer release()
+	object := s.objectPath(entry.Hash)
+	if _, err := os.Stat(object); err != nil {
+		if err := writeFileAtomic(object, data); err != nil {
+			return BackupEntry{}, NewError(ErrCodeIO, "failed to write backup", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return BackupEntry{}, NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+	catalog, err := os.OpenFile(filepath.Join(s.dir, backupCatalog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err == nil {
+		_, err = catalog.Write(append(line, '\n'))
+		if closeErr := catalog.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return BackupEntry{}, NewError(ErrCodeIO, "failed to record backup", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	return entry, nil
+}
+
+// List backups de path (todos, se vazio), mais recentes primeiro
+func (s *BackupStore) List(path string) ([]BackupEntry, error) {
+	if path != "" {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+	}
+	s.lock.Lock()
+	entries, err := s.readCatalog()
+	s.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	matched := []BackupEntry{}
+	for _, entry := range entries {
+		if path == "" || entry.Path == path {
+			matched = append(matched, entry)
+		}
+	}
+	sort.SliceStable(matched, func(a, b int) bool { return matched[a].Time.After(matched[b].Time) })
+	return matched, nil
+}
+
+// Restore devolve path à versão mais recente guardada até at (a mais
+// recente de todas, se at for zero). O conteúdo atual, se existir e for
+// diferente, é guardado antes, então uma restauração também pode ser desfeita.
+// A escrita é registrada em audit, se não for nil.
+func (s *BackupStore) Restore(path string, at time.Time, audit *AuditLog) (BackupEntry, error) {
+	entries, err := s.List(path)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	var chosen *BackupEntry
+	for i := range entries {
+		if at.IsZero() || !entries[i].Time.After(at) {
+			chosen = &entries[i]
+			break
+		}
+	}
+	if chosen == nil {
+		details := map[string]interface{}{"path": path}
+		if !at.IsZero() {
+			details["at"] = at
+		}
+		return BackupEntry{}, NewError(ErrCodeInvalidArgument, "no backup found", details)
+	}
+
+	data, err := os.ReadFile(s.objectPath(chosen.Hash))
+	if err != nil || hashContent(data) != chosen.Hash {
+		return BackupEntry{}, NewError(ErrCodeIO, "backup content is missing or damaged", map[string]interface{}{"path": chosen.Path, "hash": chosen.Hash})
+	}
+	current, err := os.ReadFile(chosen.Path)
+	switch {
+	case err == nil && bytes.Equal(current, data):
+		return *chosen, nil
+	case err == nil:
+		if _, err := s.Save(chosen.Path, current); err != nil {
+			return BackupEntry{}, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		// Arquivo removido: é recriado
+		current = nil
+	default:
+		return BackupEntry{}, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": chosen.Path, "cause": err.Error()})
+	}
+	if err := writeRecorded(audit, AuditRestore, chosen.Path, current, string(data), nil, nil, ""); err != nil {
+		return BackupEntry{}, err
+	}
+	return *chosen, nil
+}
+
+// Prune aplica a retenção: descarta os backups mais antigos que MaxAgeDays
+// e, enquanto o total passar de MaxBytes, os mais antigos restantes. Objetos
+// que nenhum backup referencia mais são apagados depois de backupObjectGrace;
+// temporários .tmp-* de gravações em andamento nunca são tocados.
+func (s *BackupStore) Prune() (PruneResult, error) {
+	release, err := s.acquire()
+	if err != nil {
+		return PruneResult{}, err
+	}
+	defer release()
+	entries, err := s.readCatalog()
+	if err != nil {
+		return PruneResult{}, err
+	}
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].Time.After(entries[b].Time) })
+
+	// Mantém os mais recentes até o primeiro que não cabe em MaxBytes; esse
+	// e todos os mais antigos são descartados
+	var kept []BackupEntry
+	sizes := map[string]int64{}
+	var total int64
+	cutoff := time.Time{}
+	if s.maxAge > 0 {
+		cutoff = time.Now().Add(-s.maxAge)
+	}
+	for _, entry := range entries {
+		if !cutoff.IsZero() && entry.Time.Before(cutoff) {
+			break
+		}
+		if _, counted := sizes[entry.Hash]; !counted {
+			if s.maxBytes > 0 && total+entry.Size > s.maxBytes {
+				break
+			}
+			sizes[entry.Hash] = entry.Size
+			total += entry.Size
+		}
+		kept = append(kept, entry)
+	}
+
+	result := PruneResult{RemovedEntries: len(entries) - len(kept), Entries: len(kept), Bytes: total}
+	if result.RemovedEntries > 0 {
+		sort.SliceStable(kept, func(a, b int) bool { return kept[a].Time.Before(kept[b].Time) })
+		var catalog bytes.Buffer
+		for _, entry := range kept {
+			line, _ := json.Marshal(entry)
+			catalog.Write(append(line, '\n'))
+		}
+		if err := writeFileAtomic(filepath.Join(s.dir, backupCatalog), catalog.Bytes()); err != nil {
+			return PruneResult{}, NewError(ErrCodeIO, "failed to rewrite backup catalog", map[string]interface{}{"dir": s.dir, "cause": err.Error()})
+		}
+	}
+
+	// Objetos órfãos
+	graceCutoff := time.Now().Add(-backupObjectGrace)
+	err = filepath.WalkDir(filepath.Join(s.dir, backupObjects), func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+		if _, referenced := sizes[entry.Name()]; referenced {
+			return nil
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil || info.ModTime().After(graceCutoff) {
+			return nil
+		}
+		if os.Remove(path) == nil {
+			result.RemovedObjects++
+			result.FreedBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, NewError(ErrCodeIO, "failed to prune backup objects", map[string]interface{}{"dir": s.dir, "cause": err.Error()})
+	}
+	return result, nil
+}
+
+// errNoBackupStore erro das operações de backup sem repositório configurado
+func errNoBackupStore() *Error {
+	return NewError(ErrCodeInvalidArgument, "backup directory not configured", nil)
+}
+
+// ListBackups como List, no repositório da instância
+func (e *Engine) ListBackups(path string) ([]BackupEntry, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.backups == nil {
+		return nil, errNoBackupStore()
+	}
+	return e.backups.List(path)
+}
+
+// RestoreBackup como Restore, no repositório e no log de auditoria da
+// instância. Falha com engine_not_running depois de Shutdown.
+func (e *Engine) RestoreBackup(path string, at time.Time) (BackupEntry, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return BackupEntry{}, acquireErr
+	}
+	defer release()
+	if e.backups == nil {
+		return BackupEntry{}, errNoBackupStore()
+	}
+	return e.backups.Restore(path, at, e.audit)
+}
+
+// PruneBackups como Prune, no repositório da instância
+func (e *Engine) PruneBackups() (PruneResult, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return PruneResult{}, acquireErr
+	}
+	defer release()
+	if e.backups == nil {
+		return PruneResult{}, errNoBackupStore()
+	}
+	return e.backups.Prune()
+}
+
+// readCatalog lê o catálogo; linhas ilegíveis (ex.: gravação interrompida)
+// são ignoradas
+func (s *BackupStore) readCatalog() ([]BackupEntry, error) {
+	file, err := os.Open(filepath.Join(s.dir, backupCatalog))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read backup catalog", map[string]interface{}{"dir": s.dir, "cause": err.Error()})
+	}
+	defer file.Close()
+
+	var entries []BackupEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var entry BackupEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Hash != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read backup catalog", map[string]interface{}{"dir": s.dir, "cause": err.Error()})
+	}
+	return entries, nil
+}
+
+func (s *BackupStore) objectPath(hash string) string {
+	return filepath.Join(s.dir, backupObjects, hash[:2], hash)
+}
+
+// ParseBackupTime aceita RFC 3339, "2006-01-02T15:04" e "2006-01-02" (hora local)
+func ParseBackupTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			// Uma data sozinha cobre o dia inteiro
+			if layout == "2006-01-02" {
+				parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+			}
+			return parsed, nil
+		}
+	}
+	return time.Time{}, NewError(ErrCodeInvalidArgument, "invalid timestamp; use RFC 3339 or YYYY-MM-DD[THH:MM]", map[string]interface{}{"value": value})
+}
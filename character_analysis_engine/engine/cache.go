@@ -0,0 +1,404 @@
+package engine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limites padrão dos níveis em memória e em disco do cache de resultados
+const (
+	DefaultResultCacheEntries      = 4096
+	DefaultResultCacheMaxBytes     = 64 << 20
+	DefaultResultCacheDiskMaxBytes = 1 << 30
+)
+
+// resultCacheFormat entra na chave: mudanças no formato do relatório ou nas
+// heurísticas devem incrementá-lo para não reaproveitar resultados antigos
+const resultCacheFormat = "demojibake-result-v2"
+
+// ResultCacheConfig cache de relatórios indexado pelo hash do conteúdo, das
+// opções e da versão do dicionário. Documentos inalterados não são
+// reanalisados; enriquecer o dicionário invalida o cache automaticamente.
+type ResultCacheConfig struct {
+	// Disabled desativa o cache
+	Disabled bool `json:"disabled"`
+
+	// Entries relatórios mantidos em memória (LRU); 0 usa o padrão
+	Entries int `json:"entries"`
+
+	// MaxBytes tamanho total, serializado, dos relatórios em memória; 0 usa o padrão
+	MaxBytes int64 `json:"maxBytes"`
+
+	// Dir diretório do nível em disco, opcional. As entradas ficam num
+	// subdiretório por versão do dicionário; quando o dicionário da instância
+	// muda, o da versão anterior é apagado.
+	Dir string `json:"dir"`
+
+	// DiskMaxBytes tamanho total das entradas em disco; acima dele as usadas
+	// há mais tempo são apagadas. 0 usa o padrão.
+	DiskMaxBytes int64 `json:"diskMaxBytes"`
+
+	// DiskMaxAgeDays apaga as entradas em disco sem uso há mais que isso; 0
+	// sem limite
+	DiskMaxAgeDays int `json:"diskMaxAgeDays"`
+}
+
+// resultCache níveis em memória (LRU) e em disco. Os relatórios são guardados
+// serializados: cada acerto devolve uma cópia independente.
+type resultCache struct {
+	entries      int
+	maxBytes     int64
+	dir          string
+	diskMaxBytes int64
+	diskMaxAge   time.Duration
+
+	lock  sync.Mutex
+	lru   *list.List
+	index map[string]*list.Element
+	bytes int64
+
+	// digests versões com que esta instância usou o disco; só os
+	// subdiretórios delas são apagados por invalidate
+	digests map[string]bool
+	// diskBytes estimativa do tamanho em disco, refeita a cada pruneDisk
+	diskBytes atomic.Int64
+	pruneLock sync.Mutex
+
+	hits          atomic.Int64
+	diskHits      atomic.Int64
+	misses        atomic.Int64
+	diskErrors    atomic.Int64
+	invalidations atomic.Int64
+}
+
+type resultCacheEntry struct {
+	key  string
+	data []byte
+}
+
+// newResultCache cria o cache, ou nil quando desativado
+func newResultCache(config ResultCacheConfig) (*resultCache, error) {
+	if config.Disabled {
+		return nil, nil
+	}
+	if config.Entries < 0 || config.MaxBytes < 0 || config.DiskMaxBytes < 0 || config.DiskMaxAgeDays < 0 {
+		return nil, NewError(ErrCodeInvalidArgument, "resultCache limits must not be negative", map[string]interface{}{
+			"entries":        config.Entries,
+			"maxBytes":       config.MaxBytes,
+			"diskMaxBytes":   config.DiskMaxBytes,
+			"diskMaxAgeDays": config.DiskMaxAgeDays,
+		})
+	}
+	cache := &resultCache{
+		entries:      config.Entries,
+		maxBytes:     config.MaxBytes,
+		dir:          config.Dir,
+		diskMaxBytes: config.DiskMaxBytes,
+		diskMaxAge:   time.Duration(config.DiskMaxAgeDays) * 24 * time.Hour,
+		lru:          list.New(),
+		index:        make(map[string]*list.Element),
+		digests:      make(map[string]bool),
+	}
+	if cache.entries == 0 {
+		cache.entries = DefaultResultCacheEntries
+	}
+	if cache.maxBytes == 0 {
+		cache.maxBytes = DefaultResultCacheMaxBytes
+	}
+	if cache.diskMaxBytes == 0 {
+		cache.diskMaxBytes = DefaultResultCacheDiskMaxBytes
+	}
+	if cache.dir != "" {
+		if err := os.MkdirAll(cache.dir, 0o755); err != nil {
+			return nil, NewError(ErrCodeIO, "failed to create result cache directory", map[string]interface{}{"dir": cache.dir, "cause": err.Error()})
+		}
+		cache.pruneDisk()
+	}
+	return cache, nil
+}
+
+// analysisFingerprint identifica tudo, além do conteúdo, que influencia o
+// relatório: formato, versão do dicionário e opções
+func analysisFingerprint(options Options, dictionaryDigest string) string {
+	encodedOptions, _ := json.Marshal(options)
+	hash := sha256.New()
+	hash.Write([]byte(resultCacheFormat))
+	hash.Write([]byte{0})
+	hash.Write([]byte(dictionaryDigest))
+	hash.Write([]byte{0})
+	hash.Write([]byte(options.vocabularyDigest))
+	hash.Write([]byte{0})
+	hash.Write(encodedOptions)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// resultCacheKey combina conteúdo, opções e versão do dicionário
+func resultCacheKey(data []byte, options Options, dictionaryDigest string) string {
+	contentHash := sha256.Sum256(data)
+	hash := sha256.New()
+	hash.Write([]byte(analysisFingerprint(options, dictionaryDigest)))
+	hash.Write([]byte{0})
+	hash.Write(contentHash[:])
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get procura o relatório na memória e depois no disco, entre as entradas
+// geradas com digest
+func (c *resultCache) get(digest, key string) *Report {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	element, ok := c.index[key]
+	var data []byte
+	if ok {
+		c.lru.MoveToFront(element)
+		data = element.Value.(*resultCacheEntry).data
+	}
+	c.digests[digest] = true
+	c.lock.Unlock()
+
+	if !ok && c.dir != "" {
+		path := c.diskPath(digest, key)
+		if stored, err := os.ReadFile(path); err == nil {
+			if report := decodeCachedReport(stored); report != nil {
+				c.diskHits.Add(1)
+				// A data de modificação marca o último uso para pruneDisk
+				now := time.Now()
+				os.Chtimes(path, now, now)
+				c.store(key, stored)
+				return report
+			}
+			c.diskErrors.Add(1)
+		} else if !os.IsNotExist(err) {
+			c.diskErrors.Add(1)
+		}
+	}
+	if !ok {
+		c.misses.Add(1)
+		return nil
+	}
+	report := decodeCachedReport(data)
+	if report == nil {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	return report
+}
+
+// put guarda o relatório nos dois níveis. Falhas no disco só são contadas:
+// o cache nunca faz a análise falhar.
+func (c *resultCache) put(digest, key string, report *Report) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	c.store(key, data)
+	if c.dir != "" {
+		if err := writeFileAtomic(c.diskPath(digest, key), data); err != nil {
+			c.diskErrors.Add(1)
+		} else if c.diskBytes.Add(int64(len(data))) > c.diskMaxBytes {
+			c.pruneDisk()
+		}
+	}
+}
+
+// store insere no nível em memória, descartando os menos usados
+func (c *resultCache) store(key string, data []byte) {
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.index[key]; ok {
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.index[key] = c.lru.PushFront(&resultCacheEntry{key: key, data: data})
+	c.bytes += int64(len(data))
+	for c.lru.Len() > c.entries || c.bytes > c.maxBytes {
+		oldest := c.lru.Back()
+		entry := oldest.Value.(*resultCacheEntry)
+		c.lru.Remove(oldest)
+		delete(c.index, entry.key)
+		c.bytes -= int64(len(entry.data))
+	}
+}
+
+// purge esvazia o nível em memória. As entradas em disco continuam válidas
+// para a versão de dicionário que as gerou e simplesmente deixam de coincidir.
+func (c *resultCache) purge() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.lru.Init()
+	c.index = make(map[string]*list.Element)
+	c.bytes = 0
+	c.lock.Unlock()
+}
+
+// invalidate descarta a memória depois de uma mudança no dicionário ou no
+// aprendizado, de previous para current, e as entradas em disco de previous
+func (c *resultCache) invalidate(previous, current string) {
+	if c == nil {
+		return
+	}
+	c.purge()
+	c.invalidations.Add(1)
+	c.lock.Lock()
+	used := c.digests[previous]
+	delete(c.digests, previous)
+	c.lock.Unlock()
+	// Entradas de versões que esta instância não usou podem ser de outra
+	// configuração que compartilha o diretório; os limites cuidam delas
+	if c.dir != "" && used && previous != current {
+		if err := os.RemoveAll(filepath.Join(c.dir, digestDir(previous))); err != nil {
+			c.diskErrors.Add(1)
+		}
+		c.pruneDisk()
+	}
+}
+
+// isCacheEntryName informa se name é um arquivo de entrada do nível em disco
+func isCacheEntryName(name string) bool {
+	key, ok := strings.CutSuffix(name, ".json")
+	if !ok || len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// digestDir subdiretório das entradas geradas com digest
+func digestDir(digest string) string {
+	sum := sha256.Sum256([]byte(digest))
+	return hex.EncodeToString(sum[:8])
+}
+
+// diskPath distribui as entradas por versão do dicionário e, dentro dela,
+// em subdiretórios pelos dois primeiros dígitos
+func (c *resultCache) diskPath(digest, key string) string {
+	return filepath.Join(c.dir, digestDir(digest), key[:2], key+".json")
+}
+
+// pruneDisk aplica os limites do nível em disco: apaga as entradas sem uso há
+// mais que diskMaxAge e mantém as usadas mais recentemente até a primeira que
+// não cabe em diskMaxBytes; essa e todas as mais antigas são apagadas. Uma
+// poda já em andamento basta.
+func (c *resultCache) pruneDisk() {
+	if !c.pruneLock.TryLock() {
+		return
+	}
+	defer c.pruneLock.Unlock()
+
+	type diskEntry struct {
+		path     string
+		size     int64
+		modified time.Time
+	}
+	var entries []diskEntry
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		// Só entradas do cache: <chave de 64 dígitos hex>.json
+		if err != nil || d.IsDir() || !isCacheEntryName(d.Name()) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			entries = append(entries, diskEntry{path: path, size: info.Size(), modified: info.ModTime()})
+		}
+		return nil
+	})
+	sort.Slice(entries, func(a, b int) bool { return entries[a].modified.After(entries[b].modified) })
+
+	var total int64
+	cutoff := time.Time{}
+	if c.diskMaxAge > 0 {
+		cutoff = time.Now().Add(-c.diskMaxAge)
+	}
+	for i, entry := range entries {
+		if entry.modified.Before(cutoff) || total+entry.size > c.diskMaxBytes {
+			for _, dropped := range entries[i:] {
+				if err := os.Remove(dropped.path); err != nil && !os.IsNotExist(err) {
+					c.diskErrors.Add(1)
+				}
+			}
+			break
+		}
+		total += entry.size
+	}
+	c.diskBytes.Store(total)
+}
+
+// metrics contadores expostos em Engine.Metrics
+func (c *resultCache) metrics() map[string]interface{} {
+	if c == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	c.lock.Lock()
+	entries, bytes := c.lru.Len(), c.bytes
+	c.lock.Unlock()
+	return map[string]interface{}{
+		"enabled":        true,
+		"hits":           c.hits.Load(),
+		"disk_hits":      c.diskHits.Load(),
+		"misses":         c.misses.Load(),
+		"disk_errors":    c.diskErrors.Load(),
+		"invalidations":  c.invalidations.Load(),
+		"entries":        entries,
+		"bytes":          bytes,
+		"max_entries":    c.entries,
+		"max_bytes":      c.maxBytes,
+		"dir":            c.dir,
+		"disk_bytes":     c.diskBytes.Load(),
+		"disk_max_bytes": c.diskMaxBytes,
+	}
+}
+
+func decodeCachedReport(data []byte) *Report {
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil
+	}
+	report.FromCache = true
+	return &report
+}
+
+// writeFileAtomic grava em arquivo temporário e renomeia, para que leitores
+// concorrentes nunca vejam uma entrada pela metade
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252HighRunes mapeia os bytes 0x80-0x9F do Windows-1252 para Unicode.
+// Posições sem caractere definido usam o próprio valor do byte (controle C1),
+// como faz o decodificador do Windows.
+var windows1252HighRunes = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// singleByteValue retorna o byte que originou r quando o texto foi decodificado
+// erroneamente como Latin-1 ou Windows-1252
+func singleByteValue(r rune) (byte, bool) {
+	if r < 0x100 {
+		return byte(r), true
+	}
+	for i, candidate := range windows1252HighRunes {
+		if candidate == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
+
+// Charsets aceitos pelo modo filtro (--from/--to)
+const (
+	CharsetAuto        = "auto"
+	CharsetUTF8        = "UTF-8"
+	CharsetISO88591    = "ISO-8859-1"
+	CharsetWindows1252 = "WINDOWS-1252"
+	CharsetASCII       = "ASCII"
+)
+
+// charsetAliases nomes alternativos, em minúsculas e sem separadores
+var charsetAliases = map[string]string{
+	"auto":        CharsetAuto,
+	"utf8":        CharsetUTF8,
+	"iso88591":    CharsetISO88591,
+	"latin1":      CharsetISO88591,
+	"l1":          CharsetISO88591,
+	"windows1252": CharsetWindows1252,
+	"cp1252":      CharsetWindows1252,
+	"ascii":       CharsetASCII,
+	"usascii":     CharsetASCII,
+}
+
+// NormalizeCharset converte nomes como "latin1" ou "cp1252" para o nome
+// canônico usado pelo motor
+func NormalizeCharset(name string) (string, error) {
+	key := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(name))
+	if charset, ok := charsetAliases[key]; ok {
+		return charset, nil
+	}
+	return "", NewError(ErrCodeInvalidArgument, "unsupported charset", map[string]interface{}{
+		"charset":   name,
+		"supported": []string{CharsetAuto, CharsetUTF8, CharsetISO88591, CharsetWindows1252, CharsetASCII},
+	})
+}
+
+// decodeCharset converte os bytes para UTF-8. Com substitute, bytes inválidos
+// viram U+FFFD; sem ele, devolvem o índice do primeiro byte inválido.
+func decodeCharset(data []byte, charset string, substitute bool) (string, int, bool) {
+	switch charset {
+	case CharsetAuto:
+		content, _ := decodeDocument(data)
+		return content, 0, true
+	case CharsetUTF8:
+		if utf8.Valid(data) {
+			return string(data), 0, true
+		}
+		if !substitute {
+			for i := 0; i < len(data); {
+				r, size := utf8.DecodeRune(data[i:])
+				if r == utf8.RuneError && size == 1 {
+					return "", i, false
+				}
+				i += size
+			}
+		}
+		return strings.ToValidUTF8(string(data), "�"), 0, true
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(data))
+	for i, b := range data {
+		switch {
+		case b < 0x80:
+			builder.WriteByte(b)
+		case charset == CharsetASCII:
+			if !substitute {
+				return "", i, false
+			}
+			builder.WriteRune(utf8.RuneError)
+		case charset == CharsetWindows1252 && b < 0xA0:
+			builder.WriteRune(windows1252HighRunes[b-0x80])
+		default:
+			builder.WriteRune(rune(b))
+		}
+	}
+	return builder.String(), 0, true
+}
+
+// encodeCharset converte texto UTF-8 para o charset de saída. Com substitute,
+// caracteres sem representação viram '?'; sem ele, devolvem o índice (em
+// bytes) do primeiro caractere sem representação.
+func encodeCharset(content, charset string, substitute bool) ([]byte, int, bool) {
+	if charset == CharsetUTF8 || charset == CharsetAuto {
+		return []byte(content), 0, true
+	}
+
+	encoded := make([]byte, 0, len(content))
+	for i, r := range content {
+		b, ok := encodeRune(r, charset)
+		if !ok {
+			if !substitute {
+				return nil, i, false
+			}
+			b = '?'
+		}
+		encoded = append(encoded, b)
+	}
+	return encoded, 0, true
+}
+
+// encodeRune retorna o byte de r no charset de byte único
+func encodeRune(r rune, charset string) (byte, bool) {
+	switch {
+	case r < 0x80:
+		return byte(r), true
+	case charset == CharsetASCII:
+		return 0, false
+	case charset == CharsetISO88591:
+		return byte(r), r <= 0xFF
+	case r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	for i, candidate := range windows1252HighRunes {
+		if candidate == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
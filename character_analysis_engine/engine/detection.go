@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeDocument detecta o encoding dos bytes e os converte para UTF-8
+func decodeDocument(data []byte) (string, string) {
+	// Detecta encoding
+	encoding := detectEncoding(data)
+
+	// Converte para UTF-8 se necessário
+	content := string(data)
+	if encoding != "UTF-8" {
+		content = convertToUTF8(data, encoding)
+	}
+
+	return content, encoding
+}
+
+func detectEncoding(data []byte) string {
+	// Detecta BOM
+	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
+		return "UTF-8"
+	}
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		return "UTF-16LE"
+	}
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		return "UTF-16BE"
+	}
+
+	// Heurística simples para detectar encoding
+	validUTF8 := utf8.Valid(data)
+	if validUTF8 {
+		return "UTF-8"
+	}
+
+	// Verifica se parece ISO-8859-1/Windows-1252
+	hasHighBytes := false
+	for _, b := range data {
+		if b >= 128 && b <= 255 {
+			hasHighBytes = true
+			break
+		}
+	}
+
+	if hasHighBytes {
+		return "ISO-8859-1"
+	}
+
+	return "ASCII"
+}
+
+func convertToUTF8(data []byte, encoding string) string {
+	switch encoding {
+	case "ISO-8859-1":
+		// Converte ISO-8859-1 para UTF-8
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(data)
+	}
+}
+
+// anomalyContextRadius bytes de cada lado da anomalia em SurroundingText
+var anomalyContextRadius = map[string]int{
+	"mojibake":         10,
+	"replacement_char": 5,
+}
+
+// detectEncodingEncodingAnomalys registra no máximo maxAnomalies anomalias
+// (0 sem limite), as de menor posição, e devolve também a contagem de todas
+// as encontradas
+func detectEncodingEncodingAnomalys(check *cancelCheck, content string, maxAnomalies int) ([]EncodingAnomaly, anomalyTally) {
+	var issues []EncodingAnomaly
+	tally := anomalyTally{}
+
+	// Padrões comuns de mojibake
+	mojibakePatterns := map[string]string{
+		"Ã¡": "á", "Ã ": "à", "Ã£": "ã", "Ã¢": "â",
+		"Ã©": "é", "Ã¨": "è", "Ãª": "ê",
+		"Ã­": "í", "Ã¬": "ì", "Ã®": "î",
+		"Ã³": "ó", "Ã²": "ò", "Ãµ": "õ", "Ã´": "ô",
+		"Ãº": "ú", "Ã¹": "ù", "Ã»": "û",
+		"Ã§": "ç", "Ã±": "ñ",
+	}
+
+	for pattern := range mojibakePatterns {
+		pos := 0
+		kept := 0
+		for !check.cancelled() {
+			index := strings.Index(content[pos:], pattern)
+			if index == -1 {
+				break
+			}
+			actualPos := pos + index
+			pos = actualPos + len(pattern)
+			tally.add("mojibake", "high", 1)
+			// Cada busca anda em ordem de posição: basta guardar as primeiras
+			if maxAnomalies > 0 && kept >= maxAnomalies {
+				continue
+			}
+			kept++
+			context := extractContext(content, actualPos, anomalyContextRadius["mojibake"])
+			issues = append(issues, EncodingAnomaly{
+				AnomalyCategory: "mojibake",
+				TextPosition:    actualPos,
+				AffectedLength:  len(pattern),
+				SurroundingText: context,
+				SeverityLevel:   "high",
+			})
+		}
+	}
+
+	// Detecta caracteres de substituição. As posições são em bytes, como as
+	// do mojibake, e o texto não é copiado para []rune.
+	kept := 0
+	for i, r := range content {
+		if check.cancelled() {
+			break
+		}
+		if r == '�' || r == '?' {
+			tally.add("replacement_char", "medium", 1)
+			if maxAnomalies > 0 && kept >= maxAnomalies {
+				continue
+			}
+			kept++
+			context := extractContext(content, i, anomalyContextRadius["replacement_char"])
+			issues = append(issues, EncodingAnomaly{
+				AnomalyCategory: "replacement_char",
+				TextPosition:    i,
+				AffectedLength:  utf8.RuneLen(r),
+				SurroundingText: context,
+				SeverityLevel:   "medium",
+			})
+		}
+	}
+
+	// Ordem estável por posição; a iteração do mapa de padrões é aleatória
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].TextPosition < issues[j].TextPosition
+	})
+	if maxAnomalies > 0 && len(issues) > maxAnomalies {
+		issues = issues[:maxAnomalies]
+	}
+	return issues, tally
+}
+
+// anomalyKey agrupa as contagens de anomalias
+type anomalyKey struct {
+	category string
+	severity string
+}
+
+// anomalyTally contagem de anomalias por categoria e severidade
+type anomalyTally map[anomalyKey]int
+
+func (t anomalyTally) add(category, severity string, n int) {
+	t[anomalyKey{category, severity}] += n
+}
+
+func (t anomalyTally) merge(other anomalyTally) {
+	for key, n := range other {
+		t[key] += n
+	}
+}
+
+func (t anomalyTally) count() int {
+	total := 0
+	for _, n := range t {
+		total += n
+	}
+	return total
+}
+
+// totals converte a contagem para o relatório, em ordem estável
+func (t anomalyTally) totals() []AnomalyTotal {
+	totals := make([]AnomalyTotal, 0, len(t))
+	for key, n := range t {
+		totals = append(totals, AnomalyTotal{Category: key.category, Severity: key.severity, Count: n})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Category != totals[j].Category {
+			return totals[i].Category < totals[j].Category
+		}
+		return totals[i].Severity < totals[j].Severity
+	})
+	return totals
+}
+
+func extractContext(content string, pos, radius int) string {
+	start := pos - radius
+	if start < 0 {
+		start = 0
+	}
+	end := pos + radius
+	if end > len(content) {
+		end = len(content)
+	}
+	return content[start:end]
+}
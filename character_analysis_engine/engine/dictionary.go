@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+)
+
+func generateBrokenKey(word string) string {
+	// Implementação do algoritmo de geração de chave quebrada
+	replacements := map[rune]string{
+		'á': "?", 'à': "?", 'ã': "?", 'â': "?", 'ä': "?",
+		'é': "?", 'è': "?", 'ê': "?", 'ë': "?",
+		'í': "?", 'ì': "?", 'î': "?", 'ï': "?",
+		'ó': "?", 'ò': "?", 'õ': "?", 'ô': "?", 'ö': "?",
+		'ú': "?", 'ù': "?", 'û': "?", 'ü': "?",
+		'ç': "??",
+		'ñ': "?",
+	}
+
+	result := []rune{}
+	for _, r := range word {
+		if replacement, ok := replacements[r]; ok {
+			result = append(result, []rune(replacement)...)
+		} else if upperReplacement, ok := replacements[unicode.ToLower(r)]; ok {
+			result = append(result, []rune(strings.ToUpper(upperReplacement))...)
+		} else {
+			result = append(result, r)
+		}
+	}
+
+	return string(result)
+}
+
+func generateVariants(word string) []string {
+	return []string{
+		strings.ToLower(word),
+		strings.Title(strings.ToLower(word)),
+		strings.ToUpper(word),
+	}
+}
+
+// Estruturas de dados especializadas implementadas
+type LanguageRadixTree struct {
+	rootLexicon    *LexiconNode
+	vocabularySize int
+}
+
+type LexiconNode struct {
+	characterIndex map[rune]*LexiconNode
+	isWordTerminal bool
+}
+
+func NewLanguageRadixTree() *LanguageRadixTree {
+	return &LanguageRadixTree{
+		rootLexicon: &LexiconNode{characterIndex: make(map[rune]*LexiconNode)},
+	}
+}
+
+func (l *LanguageRadixTree) InsertVocabulary(terminology string) {
+	currentNode := l.rootLexicon
+	for _, character := range terminology {
+		if currentNode.characterIndex[character] == nil {
+			currentNode.characterIndex[character] = &LexiconNode{characterIndex: make(map[rune]*LexiconNode)}
+		}
+		currentNode = currentNode.characterIndex[character]
+	}
+	if !currentNode.isWordTerminal {
+		currentNode.isWordTerminal = true
+		l.vocabularySize++
+	}
+}
+
+func (l *LanguageRadixTree) SearchVocabulary(terminology string) bool {
+	currentNode := l.rootLexicon
+	for _, character := range terminology {
+		if currentNode.characterIndex[character] == nil {
+			return false
+		}
+		currentNode = currentNode.characterIndex[character]
+	}
+	return currentNode.isWordTerminal
+}
+
+func (l *LanguageRadixTree) GetVocabularyCount() int { return l.vocabularySize }
+
+type FrequencyBloomFilter struct {
+	probabilisticBitArray []bool
+	arrayCapacity         int
+	hashFunctionCount     int
+}
+
+func NewFrequencyBloomFilter(capacity int, hashCount int) *FrequencyBloomFilter {
+	return &FrequencyBloomFilter{
+		probabilisticBitArray: make([]bool, capacity),
+		arrayCapacity:         capacity,
+		hashFunctionCount:     hashCount,
+	}
+}
+
+func (b *FrequencyBloomFilter) hash(word string, seed int) int {
+	hash := seed
+	for _, char := range word {
+		hash = hash*31 + int(char)
+	}
+	return (hash%b.arrayCapacity + b.arrayCapacity) % b.arrayCapacity
+}
+
+func (b *FrequencyBloomFilter) Add(word string) {
+	for i := 0; i < b.hashFunctionCount; i++ {
+		index := b.hash(word, i)
+		b.probabilisticBitArray[index] = true
+	}
+}
+
+func (b *FrequencyBloomFilter) Contains(word string) bool {
+	for i := 0; i < b.hashFunctionCount; i++ {
+		index := b.hash(word, i)
+		if !b.probabilisticBitArray[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *FrequencyBloomFilter) Size() int { return b.arrayCapacity }
+
+type ContextualNgramAnalyzer struct {
+	bigramFrequencies  map[string]int
+	trigramFrequencies map[string]int
+	totalSequenceCount int
+}
+
+func LoadContextualNgramAnalyzer(vocabularyData []byte) *ContextualNgramAnalyzer {
+	analyzer := &ContextualNgramAnalyzer{
+		bigramFrequencies:  make(map[string]int),
+		trigramFrequencies: make(map[string]int),
+	}
+	// Processa dados linguísticos para criar sequências contextuais
+	terminology := parseLanguageDictionary(vocabularyData)
+	for _, term := range terminology {
+		analyzer.incorporateTerminology(term)
+	}
+	return analyzer
+}
+
+func (c *ContextualNgramAnalyzer) incorporateTerminology(terminology string) {
+	characterSequences := []rune(terminology)
+	for i := 0; i < len(characterSequences)-1; i++ {
+		bigramSequence := string(characterSequences[i : i+2])
+		c.bigramFrequencies[bigramSequence]++
+		c.totalSequenceCount++
+	}
+	for i := 0; i < len(characterSequences)-2; i++ {
+		trigramSequence := string(characterSequences[i : i+3])
+		c.trigramFrequencies[trigramSequence]++
+	}
+}
+
+func (c *ContextualNgramAnalyzer) CalculateSequenceProbability(ngramSequence string) float64 {
+	sequenceLength := len([]rune(ngramSequence))
+	if sequenceLength == 2 {
+		return float64(c.bigramFrequencies[ngramSequence]) / float64(c.totalSequenceCount)
+	}
+	if sequenceLength == 3 {
+		return float64(c.trigramFrequencies[ngramSequence]) / float64(c.totalSequenceCount)
+	}
+	return 0.0
+}
+
+func (c *ContextualNgramAnalyzer) GetAnalyzerCapacity() int {
+	return len(c.bigramFrequencies) + len(c.trigramFrequencies)
+}
+
+func (c *ContextualNgramAnalyzer) GetProbability(text string) float64 {
+	// Calculate average probability of all n-grams in the text
+	runes := []rune(text)
+	if len(runes) < 2 {
+		return 0.0
+	}
+
+	totalProb := 0.0
+	count := 0
+
+	// Calculate bigram probabilities
+	for i := 0; i < len(runes)-1; i++ {
+		bigram := string(runes[i : i+2])
+		totalProb += c.CalculateSequenceProbability(bigram)
+		count++
+	}
+
+	// Calculate trigram probabilities
+	for i := 0; i < len(runes)-2; i++ {
+		trigram := string(runes[i : i+3])
+		totalProb += c.CalculateSequenceProbability(trigram)
+		count++
+	}
+
+	if count == 0 {
+		return 0.0
+	}
+
+	return totalProb / float64(count)
+}
+
+func parseDictionary(data []byte) []string {
+	var words []string
+	// Assume formato binário: [4 bytes length][word][4 bytes length][word]...
+	offset := 0
+	for offset < len(data)-4 {
+		// Lê tamanho da palavra (4 bytes)
+		if offset+4 > len(data) {
+			break
+		}
+		length := int(data[offset]) | int(data[offset+1])<<8 | int(data[offset+2])<<16 | int(data[offset+3])<<24
+		offset += 4
+
+		// Lê palavra
+		if offset+length > len(data) || length <= 0 {
+			break
+		}
+		word := string(data[offset : offset+length])
+		words = append(words, word)
+		offset += length
+	}
+
+	// Fallback para palavras básicas se não conseguir ler o binário
+	if len(words) == 0 {
+		words = []string{"ação", "não", "são", "então", "coração", "informação", "situação", "educação", "população", "administração"}
+	}
+
+	return words
+}
+
+// parseLanguageDictionary is an alias for parseDictionary for consistency
+func parseLanguageDictionary(data []byte) []string {
+	return parseDictionary(data)
+}
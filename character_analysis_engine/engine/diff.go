@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDiffContext linhas de contexto em volta de cada trecho alterado
+const DefaultDiffContext = 3
+
+// DiffOptions formato do diff. Com Git os cabeçalhos seguem o formato de
+// git diff (a/ e b/, caminhos relativos a Root ou ao diretório atual), e a
+// concatenação dos diffs de um lote pode ser aplicada com git apply.
+type DiffOptions struct {
+	Context int    `json:"context"`
+	Git     bool   `json:"git"`
+	Root    string `json:"root,omitempty"`
+}
+
+// DefaultDiffOptions diff unificado com o contexto padrão
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{Context: DefaultDiffContext}
+}
+
+// DocumentDiff correções de um documento em formato de diff unificado.
+// Diff fica vazio quando não há o que corrigir.
+type DocumentDiff struct {
+	Path        string `json:"path"`
+	Diff        string `json:"diff"`
+	Hunks       int    `json:"hunks"`
+	Additions   int    `json:"additions"`
+	Deletions   int    `json:"deletions"`
+	Corrections int    `json:"corrections"`
+	SkipReason  string `json:"skipReason,omitempty"`
+}
+
+// Patch diffs de um lote; Patch é a concatenação dos que têm alterações
+type Patch struct {
+	Patch string          `json:"patch"`
+	Files []*DocumentDiff `json:"files"`
+}
+
+// Diff lê path, resolve as correções e as devolve como diff unificado, sem
+// gravar nada. As linhas removidas são as do arquivo em disco: se ele não
+// está em UTF-8, a conversão troca todas as linhas e o diff cobre o arquivo
+// inteiro.
+func (e *Engine) Diff(ctx context.Context, path string, options Options, diffOptions DiffOptions) (*DocumentDiff, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	options, effective, err := e.OptionsForPath(path, options)
+	if err != nil {
+		return nil, err
+	}
+	if effective != nil && effective.Ignored {
+		return &DocumentDiff{Path: path, SkipReason: skipReasonIgnored}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to open document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	defer file.Close()
+	data, tooLarge, readErr := readLimited(file, options)
+	if readErr != nil {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": readErr.Error()})
+	}
+	if tooLarge {
+		return nil, NewError(ErrCodeLimitExceeded, fileSizeWarning(options).Message, map[string]interface{}{"path": path, "limit": "max_file_size"})
+	}
+
+	content, fixed, report, err := e.fixDocument(ctx, data, options)
+	if err != nil {
+		return nil, err
+	}
+	result := &DocumentDiff{Path: path, Corrections: len(report.SuggestedTransforms)}
+	if report.Status == StatusSkipped {
+		result.SkipReason = report.SkipReason
+		return result, nil
+	}
+	if len(report.SuggestedTransforms) == 0 || fixed == string(data) {
+		return result, nil
+	}
+
+	var changes []lineChange
+	original := splitLines(string(data))
+	if content == string(data) && len(original) > 0 {
+		changes, err = editLineChanges(content, original, report.SuggestedTransforms)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		changes = []lineChange{{start: 0, end: len(original), lines: splitLines(fixed)}}
+	}
+	oldName, newName := diffNames(path, diffOptions)
+	result.Diff, result.Hunks, result.Additions, result.Deletions = formatUnifiedDiff(oldName, newName, original, changes, diffOptions)
+	return result, nil
+}
+
+// DiffFiles Diff de cada caminho, na ordem dada
+func (e *Engine) DiffFiles(ctx context.Context, paths []string, options Options, diffOptions DiffOptions) (*Patch, error) {
+	patch := &Patch{Files: []*DocumentDiff{}}
+	var builder strings.Builder
+	for _, path := range paths {
+		diff, err := e.Diff(ctx, path, options, diffOptions)
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString(diff.Diff)
+		patch.Files = append(patch.Files, diff)
+	}
+	patch.Patch = builder.String()
+	return patch, nil
+}
+
+// lineChange troca as linhas [start, end) do original por lines
+type lineChange struct {
+	start int
+	end   int
+	lines []string
+}
+
+// splitLines divide o texto em linhas, cada uma com o seu \n; a última pode
+// não ter
+func splitLines(text string) []string {
+	var lines []string
+	for len(text) > 0 {
+		index := strings.IndexByte(text, '\n')
+		if index < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:index+1])
+		text = text[index+1:]
+	}
+	return lines
+}
+
+// editLineChanges agrupa as edições pelas linhas que tocam. Um trecho
+// alterado sempre termina em fim de linha: se a correção removeu o \n, o
+// trecho se estende à linha seguinte.
+func editLineChanges(content string, lines []string, transformations []TextTransformation) ([]lineChange, error) {
+	edits := make([]TextTransformation, len(transformations))
+	copy(edits, transformations)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].DocumentPosition < edits[j].DocumentPosition
+	})
+
+	starts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		starts[i+1] = starts[i] + len(line)
+	}
+	lineOf := func(offset int) int {
+		line := sort.Search(len(lines), func(i int) bool { return starts[i+1] > offset })
+		return min(line, len(lines)-1)
+	}
+
+	var changes []lineChange
+	for next := 0; next < len(edits); {
+		first := next
+		start := lineOf(edits[first].DocumentPosition)
+		end := start + 1
+		var fixed string
+		for {
+			for next < len(edits) && (next == first || end == len(lines) || edits[next].DocumentPosition < starts[end]) {
+				end = max(end, lineOf(max(transformationEnd(edits[next])-1, edits[next].DocumentPosition))+1)
+				next++
+			}
+			block := make([]TextTransformation, next-first)
+			for i, edit := range edits[first:next] {
+				edit.DocumentPosition -= starts[start]
+				block[i] = edit
+			}
+			var err error
+			fixed, err = ApplyTransformations(content[starts[start]:starts[end]], block)
+			if err != nil {
+				return nil, err
+			}
+			if end == len(lines) || strings.HasSuffix(fixed, "\n") {
+				break
+			}
+			end++
+		}
+		changes = append(changes, lineChange{start: start, end: end, lines: splitLines(fixed)})
+	}
+	return changes, nil
+}
+
+// diffNames cabeçalhos --- e +++ do diff
+func diffNames(path string, options DiffOptions) (string, string) {
+	if !options.Git {
+		return path, path
+	}
+	name := filepath.ToSlash(path)
+	root := options.Root
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	if absPath, err := filepath.Abs(path); err == nil && root != "" {
+		if absRoot, err := filepath.Abs(root); err == nil {
+			if rel, err := filepath.Rel(absRoot, absPath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				name = filepath.ToSlash(rel)
+			}
+		}
+	}
+	name = strings.TrimLeft(name, "/")
+	return "a/" + name, "b/" + name
+}
+
+// formatUnifiedDiff escreve as alterações como diff unificado, juntando no
+// mesmo trecho (hunk) as que estão a até 2*Context linhas uma da outra
+func formatUnifiedDiff(oldName, newName string, original []string, changes []lineChange, options DiffOptions) (string, int, int, int) {
+	context := max(options.Context, 0)
+	var builder strings.Builder
+	if options.Git {
+		fmt.Fprintf(&builder, "diff --git %s %s\n", oldName, newName)
+	}
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+
+	hunks, additions, deletions, delta := 0, 0, 0, 0
+	for first := 0; first < len(changes); {
+		last := first
+		for last+1 < len(changes) && changes[last+1].start-changes[last].end <= 2*context {
+			last++
+		}
+		oldStart := max(changes[first].start-context, 0)
+		oldEnd := min(changes[last].end+context, len(original))
+		newCount := oldEnd - oldStart
+		for _, change := range changes[first : last+1] {
+			newCount += len(change.lines) - (change.end - change.start)
+		}
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n", hunkRange(oldStart, oldEnd-oldStart), hunkRange(oldStart+delta, newCount))
+
+		cursor := oldStart
+		for _, change := range changes[first : last+1] {
+			for _, line := range original[cursor:change.start] {
+				writeDiffLine(&builder, ' ', line)
+			}
+			for _, line := range original[change.start:change.end] {
+				writeDiffLine(&builder, '-', line)
+			}
+			for _, line := range change.lines {
+				writeDiffLine(&builder, '+', line)
+			}
+			deletions += change.end - change.start
+			additions += len(change.lines)
+			delta += len(change.lines) - (change.end - change.start)
+			cursor = change.end
+		}
+		for _, line := range original[cursor:oldEnd] {
+			writeDiffLine(&builder, ' ', line)
+		}
+		hunks++
+		first = last + 1
+	}
+	return builder.String(), hunks, additions, deletions
+}
+
+// hunkRange intervalo de um cabeçalho @@; sem linhas, aponta a anterior
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// writeDiffLine escreve uma linha do diff, marcando a falta de \n no fim
+func writeDiffLine(builder *strings.Builder, prefix byte, line string) {
+	builder.WriteByte(prefix)
+	builder.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		builder.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,12 @@
+// Package engine é o núcleo de análise de codificação do Demojibakelizador:
+// detecção de encoding e de mojibake, dicionário linguístico, modelo de
+// n-gramas e geração das correções sugeridas.
+//
+// O pacote é Go puro e pode ser importado diretamente por serviços Go:
+//
+//	report, err := engine.Analyze(ctx, file, engine.DefaultOptions())
+//
+// Para corpus próprio, vocabulário adicional ou isolamento entre
+// configurações, crie instâncias com New. A biblioteca compartilhada usada
+// pelo workbench Java é apenas um adaptador cgo sobre este pacote.
+package engine
@@ -0,0 +1,618 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Version versão do motor, registrada no log de auditoria. Definida via
+// -ldflags "-X demojibake/engine.Version=..." no Makefile.
+var Version = "dev"
+
+// Config configuração de uma instância do motor
+type Config struct {
+	// Workers número de workers do pool de lote; 0 usa runtime.NumCPU()
+	Workers int `json:"workers"`
+
+	// UseEmbeddedCorpus quando false ignora Corpus e usa apenas Vocabulary
+	// e o vocabulário básico (padrão true)
+	UseEmbeddedCorpus *bool `json:"useEmbeddedCorpus"`
+
+	// Vocabulary palavras adicionadas ao dicionário desta instância
+	Vocabulary []string `json:"vocabulary"`
+
+	// DefaultOptions opções aplicadas antes das opções de cada chamada
+	DefaultOptions json.RawMessage `json:"defaultOptions"`
+
+	// SegmentSize tamanho dos segmentos da análise paralela de um único
+	// documento (opção "parallel"); 0 usa DefaultSegmentSize
+	SegmentSize int `json:"segmentSize"`
+
+	// PathPolicy restrições de caminhos aplicadas pelos hosts (ver policy.go)
+	PathPolicy PathPolicyConfig `json:"pathPolicy"`
+
+	// ResultCache cache de relatórios por conteúdo (ver cache.go)
+	ResultCache ResultCacheConfig `json:"resultCache"`
+
+	// JobDirectory diretório dos diários de jobs retomáveis (ver job.go)
+	JobDirectory string `json:"jobDirectory"`
+
+	// TransactionDirectory diretório das transações de correção (ver transaction.go)
+	TransactionDirectory string `json:"transactionDirectory"`
+
+	// Backups repositório dos originais das correções gravadas (ver backup.go)
+	Backups BackupStoreConfig `json:"backups"`
+
+	// AuditLog arquivo JSONL que registra cada escrita em documentos (ver audit.go)
+	AuditLog string `json:"auditLog"`
+
+	// FeedbackFile decisões dos revisores que ajustam as pontuações (ver feedback.go)
+	FeedbackFile string `json:"feedbackFile"`
+
+	// ProjectConfig procura .demojibake.json a partir de cada documento e
+	// aplica as opções de projeto (ver project.go)
+	ProjectConfig bool `json:"projectConfig"`
+
+	// Corpus dicionário linguístico no formato binário de parseDictionary.
+	// O shim C embute o corpus português e o repassa aqui.
+	Corpus []byte `json:"-"`
+}
+
+// Engine instância independente do motor. Cada instância tem seu próprio
+// dicionário, modelo de n-gramas, pool de workers e contadores, e pode ser
+// usada por várias goroutines ao mesmo tempo.
+type Engine struct {
+	config         Config
+	defaultOptions Options
+	pathPolicy     *PathPolicy
+
+	// Dicionário da instância, protegido por dictionaryLock. dictionaryDigest
+	// identifica o conteúdo do dicionário e entra na chave do cache de resultados.
+	dictionaryLock   sync.RWMutex
+	dictTrie         *LanguageRadixTree
+	dictBloom        *FrequencyBloomFilter
+	dictCache        map[string]string
+	ngramModel       *ContextualNgramAnalyzer
+	dictionaryDigest string
+
+	resultCache *resultCache
+	backups     *BackupStore
+	audit       *AuditLog
+	feedback    *FeedbackStore
+	projects    projectConfigs
+
+	concurrentProcessorPool *ConcurrentProcessorPool
+	segmentSize             int
+	segmentSlots            chan struct{}
+	totalFiles              atomic.Int64
+	processing              atomic.Int64
+
+	// Ciclo de vida: ver lifecycle.go
+	lifecycleLock sync.RWMutex
+	state         int32
+	inFlight      sync.WaitGroup
+	runContext    context.Context
+	cancelRun     context.CancelFunc
+}
+
+// ParseConfig decodifica uma configuração JSON. Ao contrário das opções de
+// análise, chaves desconhecidas são rejeitadas: um erro de digitação aqui
+// mudaria silenciosamente o comportamento de toda a instância.
+func ParseConfig(jsonStr string) (Config, error) {
+	var config Config
+	if len(bytes.TrimSpace([]byte(jsonStr))) == 0 {
+		return config, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(jsonStr)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, NewError(ErrCodeInvalidArgument, "invalid engine config", map[string]interface{}{"cause": err.Error()})
+	}
+	return config, nil
+}
+
+// New cria e inicializa uma instância com a configuração dada. Se um passo
+// falha, o que os anteriores criaram é desfeito antes do retorno.
+func New(config Config) (_ *Engine, err error) {
+	if config.Workers < 0 {
+		return nil, NewError(ErrCodeInvalidArgument, "workers must not be negative", map[string]interface{}{"workers": config.Workers})
+	}
+	workers := config.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	if config.SegmentSize < 0 {
+		return nil, NewError(ErrCodeInvalidArgument, "segmentSize must not be negative", map[string]interface{}{"segmentSize": config.SegmentSize})
+	}
+	segmentSize := config.SegmentSize
+	if segmentSize == 0 {
+		segmentSize = DefaultSegmentSize
+	}
+
+	defaults := DefaultOptions()
+	if len(config.DefaultOptions) > 0 {
+		options, warnings, err := ParseOptions(string(config.DefaultOptions), defaults)
+		if err != nil {
+			return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+		}
+		if len(warnings) > 0 {
+			return nil, NewError(ErrCodeInvalidOptions, warnings[0].Message, nil)
+		}
+		// Os padrões da instância não contam como opções da chamada
+		options.explicit = nil
+		defaults = options
+	}
+
+	pathPolicy, err := NewPathPolicy(config.PathPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Desfaz, na ordem inversa, o que já foi criado quando um passo seguinte falha
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+		}
+	}()
+
+	pool := NewConcurrentProcessorPool(workers)
+	undo = append(undo, func() { pool.Stop(0) })
+
+	cache, err := newResultCache(config.ResultCache)
+	if err != nil {
+		return nil, err
+	}
+	undo = append(undo, cache.purge)
+
+	var audit *AuditLog
+	if config.AuditLog != "" {
+		if audit, err = OpenAuditLog(config.AuditLog); err != nil {
+			return nil, err
+		}
+	}
+
+	var feedback *FeedbackStore
+	if config.FeedbackFile != "" {
+		if feedback, err = OpenFeedbackStore(config.FeedbackFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// A retenção dos backups, se configurada, é aplicada a cada nova instância
+	var backups *BackupStore
+	if config.Backups.Dir != "" {
+		if backups, err = OpenBackupStore(config.Backups); err != nil {
+			return nil, err
+		}
+		if backups.HasRetention() {
+			if _, err = backups.Prune(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var corpus []byte
+	if config.UseEmbeddedCorpus == nil || *config.UseEmbeddedCorpus {
+		corpus = config.Corpus
+	}
+
+	engine := &Engine{
+		config:                  config,
+		defaultOptions:          defaults,
+		pathPolicy:              pathPolicy,
+		dictTrie:                NewLanguageRadixTree(),
+		dictBloom:               NewFrequencyBloomFilter(1000000, 5),
+		dictCache:               make(map[string]string, 100000),
+		ngramModel:              LoadContextualNgramAnalyzer(corpus),
+		resultCache:             cache,
+		backups:                 backups,
+		audit:                   audit,
+		feedback:                feedback,
+		concurrentProcessorPool: pool,
+		segmentSize:             segmentSize,
+		segmentSlots:            make(chan struct{}, workers),
+	}
+
+	engine.projects.policy = pathPolicy
+
+	// Carrega o corpus e o vocabulário próprio da instância
+	engine.enrichDictionary(parseLanguageDictionary(corpus))
+	engine.enrichDictionary(config.Vocabulary)
+
+	engine.runContext, engine.cancelRun = context.WithCancel(context.Background())
+	engine.concurrentProcessorPool.Start()
+	engine.state = engineStateRunning
+	return engine, nil
+}
+
+var (
+	// Instância compartilhada usada pela função Analyze do pacote
+	sharedEngine     *Engine
+	sharedEngineErr  error
+	sharedEngineOnce sync.Once
+)
+
+// Analyze analisa o conteúdo de r com uma instância compartilhada que usa
+// apenas o vocabulário básico. Serviços que precisam de corpus próprio ou de
+// isolamento devem criar a instância com New.
+func Analyze(ctx context.Context, r io.Reader, options Options) (*Report, error) {
+	sharedEngineOnce.Do(func() {
+		sharedEngine, sharedEngineErr = New(Config{})
+	})
+	if sharedEngineErr != nil {
+		return nil, sharedEngineErr
+	}
+	return sharedEngine.Analyze(ctx, r, options)
+}
+
+// DefaultOptions retorna as opções padrão desta instância
+func (e *Engine) DefaultOptions() Options {
+	return e.defaultOptions
+}
+
+// Analyze lê todo o conteúdo de r e devolve o relatório de análise
+func (e *Engine) Analyze(ctx context.Context, r io.Reader, options Options) (*Report, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	data, tooLarge, readErr := readLimited(r, options)
+	if readErr != nil {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"cause": readErr.Error()})
+	}
+	if tooLarge {
+		return limitSkippedReport("", skipReasonTooLarge, fileSizeWarning(options), options), nil
+	}
+	return e.analyzeContent(ctx, "", data, options)
+}
+
+// JobDirectory diretório dos diários de jobs configurado na instância
+func (e *Engine) JobDirectory() string {
+	return e.config.JobDirectory
+}
+
+// AuditLog log de auditoria da instância; nil quando não configurado
+func (e *Engine) AuditLog() *AuditLog {
+	return e.audit
+}
+
+// Backups repositório de backups da instância; nil quando não configurado
+func (e *Engine) Backups() *BackupStore {
+	return e.backups
+}
+
+// TransactionDirectory diretório das transações configurado na instância
+func (e *Engine) TransactionDirectory() string {
+	return e.config.TransactionDirectory
+}
+
+// PathPolicy política de caminhos configurada na criação da instância
+func (e *Engine) PathPolicy() *PathPolicy {
+	return e.pathPolicy
+}
+
+// AnalyzeFile analisa o documento em path. Não aplica a política de
+// caminhos: hosts que recebem caminhos de terceiros devem chamar
+// PathPolicy().CheckFile antes.
+func (e *Engine) AnalyzeFile(ctx context.Context, path string, options Options) (*Report, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return e.analyzePath(ctx, path, options)
+}
+
+// analyzePath lê e analisa o documento em path
+func (e *Engine) analyzePath(ctx context.Context, path string, options Options) (*Report, error) {
+	options, effective, err := e.OptionsForPath(path, options)
+	if err != nil {
+		return nil, err
+	}
+	if effective != nil && effective.Ignored {
+		return effective.IgnoredReport(), nil
+	}
+	if options.MaxFileSize > 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() > options.MaxFileSize {
+			return limitSkippedReport(path, skipReasonTooLarge, fileSizeWarning(options), options), nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	report, err := e.analyzeContent(ctx, path, data, options)
+	return withProjectWarnings(report, effective), err
+}
+
+// AnalyzeFiles distribui os documentos entre os workers da instância e chama
+// visit (de forma concorrente) com o resultado de cada um. Retorna quando
+// todos os documentos foram processados.
+func (e *Engine) AnalyzeFiles(ctx context.Context, paths []string, options Options, visit func(path string, report *Report, err error)) error {
+	release, err := e.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	e.totalFiles.Store(int64(len(paths)))
+	e.processing.Store(0)
+
+	// Processa em paralelo; o WaitGroup é do lote, não do pool compartilhado
+	var batch sync.WaitGroup
+	for _, path := range paths {
+		if err := e.submitAnalysis(ctx, &batch, path, options, visit); err != nil {
+			batch.Wait()
+			return err
+		}
+	}
+
+	// Aguarda conclusão
+	batch.Wait()
+	return nil
+}
+
+// submitAnalysis entrega a análise de um documento ao pool, contando-a em batch
+func (e *Engine) submitAnalysis(ctx context.Context, batch *sync.WaitGroup, path string, options Options, visit func(path string, report *Report, err error)) error {
+	return e.submitTask(ctx, batch, func(taskCtx context.Context) {
+		// Processa arquivo
+		report, err := e.analyzePath(taskCtx, path, options)
+		visit(path, report, err)
+	})
+}
+
+// submitTask entrega uma tarefa ao pool, contando-a em batch e em processing
+func (e *Engine) submitTask(ctx context.Context, batch *sync.WaitGroup, task func(ctx context.Context)) error {
+	batch.Add(1)
+	submitErr := e.concurrentProcessorPool.Submit(func(poolCtx context.Context) {
+		defer batch.Done()
+		e.processing.Add(1)
+		taskCtx, cancel := mergeContexts(ctx, poolCtx)
+		defer cancel()
+		task(taskCtx)
+	})
+	if submitErr != nil {
+		batch.Done()
+		return errNotRunning()
+	}
+	return nil
+}
+
+// analyzeContent decodifica os bytes do documento e executa a análise.
+// Conteúdo binário não é decodificado: o relatório sai com status skipped.
+func (e *Engine) analyzeContent(ctx context.Context, path string, data []byte, options Options) (*Report, error) {
+	class := classifySample(data)
+	if class.Class == ContentBinary {
+		return skippedReport(path, class, options), nil
+	}
+
+	// Conteúdo já analisado com as mesmas opções e o mesmo dicionário
+	var cacheKey, digest string
+	if e.resultCache != nil {
+		digest = e.analysisDigest()
+		cacheKey = resultCacheKey(data, options, digest)
+		if cached := e.resultCache.get(digest, cacheKey); cached != nil {
+			cached.DocumentPath = path
+			cached.ContentSHA256 = hashContent(data)
+			return cached, nil
+		}
+	}
+
+	ctx, cancel := withAnalysisTimeout(ctx, options)
+	defer cancel()
+
+	content, encoding := decodeDocument(data)
+	report, err := e.analyzeText(ctx, path, content, encoding, options)
+	if err != nil {
+		if timedOut(ctx) {
+			return limitSkippedReport(path, skipReasonTimeout, timeoutWarning(options), options), nil
+		}
+		return nil, err
+	}
+	report.ContentClass = &class
+	report.ContentSHA256 = hashContent(data)
+
+	// Um Enrich ou uma decisão de revisor durante a análise muda as
+	// pontuações: o resultado não é guardado
+	if cacheKey != "" && e.analysisDigest() == digest {
+		e.resultCache.put(digest, cacheKey, report)
+	}
+	return report, nil
+}
+
+// currentDictionaryDigest versão atual do dicionário
+func (e *Engine) currentDictionaryDigest() string {
+	e.dictionaryLock.RLock()
+	defer e.dictionaryLock.RUnlock()
+	return e.dictionaryDigest
+}
+
+// skippedReport relatório de um documento binário que não foi analisado
+func skippedReport(path string, class ContentClassification, options Options) *Report {
+	return &Report{
+		DocumentPath:        path,
+		Status:              StatusSkipped,
+		SkipReason:          ContentBinary,
+		ContentClass:        &class,
+		EncodingAnomalies:   []EncodingAnomaly{},
+		SuggestedTransforms: []TextTransformation{},
+		EffectiveOptions:    options,
+	}
+}
+
+// analyzeText executa a detecção e as correções sobre o conteúdo já em UTF-8
+func (e *Engine) analyzeText(ctx context.Context, path, content, encoding string, options Options) (*Report, error) {
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+	ctx, cancel := mergeContexts(ctx, e.runContext)
+	defer cancel()
+
+	startTime := time.Now()
+	result := &Report{
+		DocumentPath:         path,
+		Status:               StatusAnalyzed,
+		SourceCharacterSet:   encoding,
+		InferredCharacterSet: "UTF-8",
+		EffectiveOptions:     options,
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errCancelled(path, err)
+	}
+
+	// Documentos grandes são divididos em segmentos analisados em paralelo
+	segments := []textSegment{{0, len(content)}}
+	if options.Parallel {
+		segments = splitSegments(content, e.segmentSize)
+	}
+
+	// Detecta problemas, aplica correções e resolve conflitos entre estratégias
+	e.dictionaryLock.RLock()
+	segmentResults, err := e.analyzeSegments(ctx, content, segments, options)
+	e.dictionaryLock.RUnlock()
+	if err != nil {
+		return nil, errCancelled(path, err)
+	}
+
+	var issues []EncodingAnomaly
+	var corrections []TextTransformation
+	tally := anomalyTally{}
+	for _, segment := range segmentResults {
+		tally.merge(segment.tally)
+		issues = append(issues, segment.issues...)
+		corrections = append(corrections, segment.corrections...)
+		result.RejectedTransforms = append(result.RejectedTransforms, segment.rejected...)
+	}
+	if options.MaxAnomalies > 0 && len(issues) > options.MaxAnomalies {
+		issues = issues[:options.MaxAnomalies]
+	}
+	total := tally.count()
+	result.EncodingAnomalies = issues
+	result.AnomalyCount = total
+	if total > 0 {
+		result.AnomalyTotals = tally.totals()
+	}
+	if total > len(issues) {
+		result.AnomaliesTruncated = true
+		result.Warnings = append(result.Warnings, anomaliesWarning(int64(total), options))
+	}
+	for i := range corrections {
+		corrections[i].ID = transformationID(corrections[i])
+	}
+	for i := range result.RejectedTransforms {
+		rejected := &result.RejectedTransforms[i].Transformation
+		rejected.ID = transformationID(*rejected)
+	}
+	result.SuggestedTransforms = corrections
+
+	// Calcula confiança
+	result.AccuracyScore = calculateConfidence(total, corrections)
+	result.TransformationSuccess = len(corrections) > 0
+	result.AnalysisDuration = time.Since(startTime)
+
+	return result, nil
+}
+
+// mergeContexts deriva de ctx um contexto que também é cancelado junto com other
+func mergeContexts(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	return merged, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Enrich adiciona palavras ao dicionário da instância
+func (e *Engine) Enrich(words []string) error {
+	release, err := e.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	previous := e.analysisDigest()
+	e.enrichDictionary(words)
+	// Os relatórios guardados foram gerados com o dicionário anterior
+	e.resultCache.invalidate(previous, e.analysisDigest())
+	return nil
+}
+
+// enrichDictionary adiciona palavras e suas variações quebradas ao dicionário
+func (e *Engine) enrichDictionary(words []string) {
+	e.dictionaryLock.Lock()
+	defer e.dictionaryLock.Unlock()
+
+	// A versão encadeia as palavras na ordem em que foram adicionadas, então é
+	// a mesma entre execuções com o mesmo corpus e vocabulário
+	digest := sha256.New()
+	digest.Write([]byte(e.dictionaryDigest))
+	for _, word := range words {
+		digest.Write([]byte(word))
+		digest.Write([]byte{'\n'})
+	}
+	e.dictionaryDigest = hex.EncodeToString(digest.Sum(nil))
+
+	for _, word := range words {
+		e.dictTrie.InsertVocabulary(word)
+		e.dictBloom.Add(word)
+
+		// Gera variações
+		for _, variant := range generateVariants(word) {
+			broken := generateBrokenKey(variant)
+			e.dictCache[broken] = variant
+		}
+	}
+}
+
+// Contains informa se a palavra (sem diferenciar maiúsculas) está no dicionário
+func (e *Engine) Contains(word string) bool {
+	e.dictionaryLock.RLock()
+	defer e.dictionaryLock.RUnlock()
+	if e.dictTrie == nil {
+		return false
+	}
+	return e.dictTrie.SearchVocabulary(strings.ToLower(word))
+}
+
+// Metrics retorna as estatísticas do dicionário e do processamento da instância
+func (e *Engine) Metrics() map[string]interface{} {
+	e.dictionaryLock.RLock()
+	defer e.dictionaryLock.RUnlock()
+
+	stats := map[string]interface{}{
+		"state":            e.State(),
+		"total_vocabulary": 0,
+		"bloom_size":       0,
+		"processing_count": e.processing.Load(),
+		"total_files":      e.totalFiles.Load(),
+		"workers":          e.concurrentProcessorPool.processorCount,
+		"default_options":  e.defaultOptions,
+		"segment_size":     e.segmentSize,
+		"result_cache":     e.resultCache.metrics(),
+	}
+	// Após o encerramento os dicionários já foram liberados
+	if e.dictTrie != nil {
+		stats["total_vocabulary"] = e.dictTrie.GetVocabularyCount()
+		stats["bloom_size"] = e.dictBloom.Size()
+		stats["contextual_analyzer_capacity"] = e.ngramModel.GetAnalyzerCapacity()
+	}
+	return stats
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"errors"
+)
+
+// Códigos de erro. São estáveis: hosts (o workbench Java, a CLI) usam o
+// código, e não a mensagem, para decidir o que exibir ao usuário.
+const (
+	ErrCodeNotInitialized  = "engine_not_initialized"
+	ErrCodeInvalidArgument = "invalid_argument"
+	ErrCodeInvalidPath     = "invalid_path"
+	ErrCodeInvalidOptions  = "invalid_options"
+	ErrCodeIO              = "io_error"
+	ErrCodeSerialization   = "serialization_failed"
+	ErrCodeInternal        = "internal_error"
+	ErrCodeInvalidHandle   = "invalid_handle"
+	ErrCodeNotRunning      = "engine_not_running"
+	ErrCodeAlreadyRunning  = "engine_already_running"
+	ErrCodeCancelled       = "cancelled"
+	ErrCodeCharset         = "charset_conversion_failed"
+	ErrCodeLimitExceeded   = "limit_exceeded"
+	ErrCodeStaleAnalysis   = "stale_analysis"
+)
+
+// Error erro estruturado com código legível por máquina
+type Error struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+// NewError cria um erro estruturado
+func NewError(code, message string, details map[string]interface{}) *Error {
+	return &Error{Code: code, Message: message, Details: details}
+}
+
+// AsError converte qualquer erro em *Error, preservando o código quando já existe
+func AsError(err error, fallbackCode string) *Error {
+	var engineErr *Error
+	if errors.As(err, &engineErr) {
+		return engineErr
+	}
+	return NewError(fallbackCode, err.Error(), nil)
+}
@@ -0,0 +1,483 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Peso das decisões dos revisores na pontuação das correções. O ajuste de
+// cada nível é maxShift*(aceitas-rejeitadas)/(aceitas+rejeitadas+feedbackPrior):
+// poucas decisões mexem pouco e o ajuste nunca passa de maxShift. A
+// estratégia inteira só é ajustada a partir de feedbackStrategyMinimum
+// decisões, para que rejeitar um padrão não derrube os vizinhos.
+const (
+	feedbackPrior           = 2
+	feedbackStrategyMinimum = 10
+	feedbackStrategyShift   = 0.1
+	feedbackPatternShift    = 0.3
+	feedbackFormat          = 1
+	maxFeedbackContext      = 64
+)
+
+// FeedbackCounts decisões acumuladas
+type FeedbackCounts struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// adjustment deslocamento da pontuação para estas decisões
+func (c FeedbackCounts) adjustment(maxShift float64) float64 {
+	return maxShift * float64(c.Accepted-c.Rejected) / float64(c.Accepted+c.Rejected+feedbackPrior)
+}
+
+// strategyAdjustment deslocamento de uma estratégia inteira
+func (c FeedbackCounts) strategyAdjustment() float64 {
+	if c.Accepted+c.Rejected < feedbackStrategyMinimum {
+		return 0
+	}
+	return c.adjustment(feedbackStrategyShift)
+}
+
+// StrategyFeedback decisões e ajuste de uma estratégia
+type StrategyFeedback struct {
+	Strategy string `json:"strategy"`
+	FeedbackCounts
+	Adjustment float64 `json:"adjustment"`
+}
+
+// PatternFeedback decisões sobre uma troca de original por replacement
+// dentro da palavra context (em minúsculas)
+type PatternFeedback struct {
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Context     string `json:"context"`
+	Strategy    string `json:"strategy"`
+	FeedbackCounts
+	Updated    time.Time `json:"updated"`
+	Adjustment float64   `json:"adjustment,omitempty"`
+}
+
+// FeedbackSummary o que foi aprendido até agora
+type FeedbackSummary struct {
+	Path       string             `json:"path"`
+	Decisions  int                `json:"decisions"`
+	Strategies []StrategyFeedback `json:"strategies"`
+	Patterns   []PatternFeedback  `json:"patterns"`
+}
+
+// feedbackFile formato persistido
+type feedbackFile struct {
+	Version    int                       `json:"version"`
+	Strategies map[string]FeedbackCounts `json:"strategies"`
+	Patterns   []PatternFeedback         `json:"patterns"`
+}
+
+type patternKey struct {
+	original, replacement, context string
+}
+
+type pairKey struct {
+	original, replacement string
+}
+
+// FeedbackStore decisões de aceite e rejeição, gravadas em um arquivo JSON.
+// Um padrão com decisões no mesmo contexto usa só elas; sem, vale o que foi
+// decidido para a mesma troca em qualquer contexto. Cada gravação segura
+// <arquivo>.lock e relê o arquivo antes de somar as decisões, para não perder
+// as de outro processo que use o mesmo arquivo.
+type FeedbackStore struct {
+	path string
+
+	lock       sync.RWMutex
+	strategies map[string]FeedbackCounts
+	patterns   map[patternKey]*PatternFeedback
+	pairs      map[pairKey]FeedbackCounts
+	digest     string
+}
+
+// OpenFeedbackStore carrega o arquivo em path; se ele não existe, começa vazio
+func OpenFeedbackStore(path string) (*FeedbackStore, error) {
+	if path == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "feedback file not configured", nil)
+	}
+	store := &FeedbackStore{path: path}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// load substitui o estado em memória pelo arquivo; o chamador mantém lock
+func (s *FeedbackStore) load() error {
+	s.clear()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to read feedback file", map[string]interface{}{"path": s.path, "cause": err.Error()})
+	}
+	var file feedbackFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return NewError(ErrCodeSerialization, "invalid feedback file", map[string]interface{}{"path": s.path, "cause": err.Error()})
+	}
+	if file.Version != feedbackFormat {
+		return NewError(ErrCodeSerialization, "unsupported feedback file version", map[string]interface{}{"path": s.path, "version": file.Version})
+	}
+	for strategy, counts := range file.Strategies {
+		s.strategies[strategy] = counts
+	}
+	for i := range file.Patterns {
+		pattern := file.Patterns[i]
+		pattern.Adjustment = 0
+		s.patterns[patternKey{pattern.Original, pattern.Replacement, pattern.Context}] = &pattern
+	}
+	s.rebuild(data)
+	return nil
+}
+
+// update aplica change sobre o conteúdo atual do arquivo e grava o
+// resultado, tudo sob o lock do arquivo
+func (s *FeedbackStore) update(change func()) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to lock feedback file", map[string]interface{}{"path": s.path, "cause": err.Error()})
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	change()
+	return s.save()
+}
+
+// Path arquivo do aprendizado
+func (s *FeedbackStore) Path() string {
+	return s.path
+}
+
+// clear esvazia o estado em memória
+func (s *FeedbackStore) clear() {
+	s.strategies = map[string]FeedbackCounts{}
+	s.patterns = map[patternKey]*PatternFeedback{}
+	s.pairs = map[pairKey]FeedbackCounts{}
+	s.digest = ""
+}
+
+// rebuild recalcula os totais por troca e a versão a partir do conteúdo gravado
+func (s *FeedbackStore) rebuild(data []byte) {
+	s.pairs = map[pairKey]FeedbackCounts{}
+	for _, pattern := range s.patterns {
+		key := pairKey{pattern.Original, pattern.Replacement}
+		counts := s.pairs[key]
+		counts.Accepted += pattern.Accepted
+		counts.Rejected += pattern.Rejected
+		s.pairs[key] = counts
+	}
+	s.digest = ""
+	if len(s.patterns) > 0 || len(s.strategies) > 0 {
+		s.digest = hashContent(data)
+	}
+}
+
+// Digest versão do aprendizado, vazia enquanto não há decisões. Entra na
+// chave do cache de resultados: uma decisão nova muda as pontuações.
+func (s *FeedbackStore) Digest() string {
+	if s == nil {
+		return ""
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.digest
+}
+
+// adjustment deslocamento aprendido para uma correção; um store nil não ajusta
+func (s *FeedbackStore) adjustment(strategy, original, replacement, context string) float64 {
+	if s == nil {
+		return 0
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.digest == "" {
+		return 0
+	}
+	shift := s.strategies[strategy].strategyAdjustment()
+	if pattern, ok := s.patterns[patternKey{original, replacement, context}]; ok {
+		return shift + pattern.adjustment(feedbackPatternShift)
+	}
+	return shift + s.pairs[pairKey{original, replacement}].adjustment(feedbackPatternShift)
+}
+
+// record soma as decisões às do arquivo e o grava
+func (s *FeedbackStore) record(decisions []PatternFeedback) error {
+	return s.update(func() {
+		s.add(decisions, time.Now().UTC())
+	})
+}
+
+// add soma as decisões ao estado em memória; o chamador mantém lock
+func (s *FeedbackStore) add(decisions []PatternFeedback, now time.Time) {
+	for _, decision := range decisions {
+		strategy := s.strategies[decision.Strategy]
+		strategy.Accepted += decision.Accepted
+		strategy.Rejected += decision.Rejected
+		s.strategies[decision.Strategy] = strategy
+
+		key := patternKey{decision.Original, decision.Replacement, decision.Context}
+		pattern, ok := s.patterns[key]
+		if !ok {
+			pattern = &PatternFeedback{Original: decision.Original, Replacement: decision.Replacement, Context: decision.Context, Strategy: decision.Strategy}
+			s.patterns[key] = pattern
+		}
+		pattern.Accepted += decision.Accepted
+		pattern.Rejected += decision.Rejected
+		pattern.Updated = now
+	}
+}
+
+// Reset esquece o que foi aprendido para strategy, ou tudo se vazio
+func (s *FeedbackStore) Reset(strategy string) error {
+	return s.update(func() {
+		if strategy == "" {
+			s.clear()
+			return
+		}
+		delete(s.strategies, strategy)
+		for key, pattern := range s.patterns {
+			if pattern.Strategy == strategy {
+				delete(s.patterns, key)
+			}
+		}
+	})
+}
+
+// save grava o estado; o chamador mantém lock
+func (s *FeedbackStore) save() error {
+	file := feedbackFile{Version: feedbackFormat, Strategies: s.strategies, Patterns: s.sortedPatterns()}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return NewError(ErrCodeIO, "failed to write feedback file", map[string]interface{}{"path": s.path, "cause": err.Error()})
+	}
+	s.rebuild(data)
+	return nil
+}
+
+// sortedPatterns padrões em ordem estável; o chamador mantém lock
+func (s *FeedbackStore) sortedPatterns() []PatternFeedback {
+	patterns := make([]PatternFeedback, 0, len(s.patterns))
+	for _, pattern := range s.patterns {
+		patterns = append(patterns, *pattern)
+	}
+	sort.Slice(patterns, func(a, b int) bool {
+		if patterns[a].Original != patterns[b].Original {
+			return patterns[a].Original < patterns[b].Original
+		}
+		if patterns[a].Replacement != patterns[b].Replacement {
+			return patterns[a].Replacement < patterns[b].Replacement
+		}
+		return patterns[a].Context < patterns[b].Context
+	})
+	return patterns
+}
+
+// Summary decisões e ajustes atuais, por estratégia e por padrão
+func (s *FeedbackStore) Summary() *FeedbackSummary {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	summary := &FeedbackSummary{Path: s.path, Strategies: []StrategyFeedback{}, Patterns: s.sortedPatterns()}
+	for strategy, counts := range s.strategies {
+		summary.Strategies = append(summary.Strategies, StrategyFeedback{Strategy: strategy, FeedbackCounts: counts, Adjustment: counts.strategyAdjustment()})
+		summary.Decisions += counts.Accepted + counts.Rejected
+	}
+	sort.Slice(summary.Strategies, func(a, b int) bool { return summary.Strategies[a].Strategy < summary.Strategies[b].Strategy })
+	for i := range summary.Patterns {
+		summary.Patterns[i].Adjustment = summary.Patterns[i].adjustment(feedbackPatternShift)
+	}
+	return summary
+}
+
+// feedbackContext palavra em minúsculas que contém o trecho [start, end),
+// sem a pontuação das bordas, como em splitWordSpans
+func feedbackContext(content string, start, end int) string {
+	if start < 0 || start > end || end > len(content) {
+		return ""
+	}
+	if previous := strings.LastIndexFunc(content[:start], unicode.IsSpace); previous >= 0 {
+		_, size := utf8.DecodeRuneInString(content[previous:])
+		start = previous + size
+	} else {
+		start = 0
+	}
+	if next := strings.IndexFunc(content[end:], unicode.IsSpace); next >= 0 {
+		end += next
+	} else {
+		end = len(content)
+	}
+	word := strings.ToLower(strings.Trim(content[start:end], ".,!?;:"))
+	if len(word) > maxFeedbackContext {
+		return ""
+	}
+	return word
+}
+
+// learnedAdjustment ajuste aprendido para a correção de original em pos
+func (e *Engine) learnedAdjustment(strategy, content string, pos int, original, corrected string) float64 {
+	if e.feedback == nil {
+		return 0
+	}
+	return e.feedback.adjustment(strategy, original, corrected, feedbackContext(content, pos, pos+len(original)))
+}
+
+// FeedbackResult resultado de RecordFeedback
+type FeedbackResult struct {
+	Path     string   `json:"path"`
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Unknown  []string `json:"unknown"`
+
+	// Mismatched IDs cuja posição ou texto não conferem com o documento
+	Mismatched []string `json:"mismatched"`
+}
+
+// RecordFeedback registra quais correções de report (sugeridas ou
+// rejeitadas) o revisor aceitou e quais rejeitou. Como em ApplySelected, o
+// arquivo precisa estar como na análise, porque o contexto de cada correção
+// vem do texto: registre antes de gravar as correções aceitas.
+func (e *Engine) RecordFeedback(ctx context.Context, path string, report *Report, accepted, rejected []string) (*FeedbackResult, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.feedback == nil {
+		return nil, NewError(ErrCodeInvalidArgument, "feedback file not configured", nil)
+	}
+
+	data, err := readAnalyzedDocument(path, report)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errCancelled(path, err)
+	}
+	content, _ := decodeDocument(data)
+	// Candidatas rejeitadas também recebem decisões: é assim que uma
+	// correção que caiu abaixo do limiar pode voltar
+	suggested := suggestedByID(report)
+	for _, rejected := range report.RejectedTransforms {
+		t := rejected.Transformation
+		if t.ID == "" {
+			t.ID = transformationID(t)
+		}
+		if _, ok := suggested[t.ID]; !ok {
+			suggested[t.ID] = t
+		}
+	}
+
+	result := &FeedbackResult{Path: path, Unknown: []string{}, Mismatched: []string{}}
+	var decisions []PatternFeedback
+	seen := map[string]bool{}
+	decide := func(ids []string, accept bool) {
+		for _, id := range ids {
+			t, ok := suggested[id]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if !ok {
+				result.Unknown = append(result.Unknown, id)
+				continue
+			}
+			if !matchesContent(content, t) {
+				result.Mismatched = append(result.Mismatched, id)
+				continue
+			}
+			decision := PatternFeedback{
+				Original:    t.OriginalSequence,
+				Replacement: t.TransformedSequence,
+				Context:     feedbackContext(content, t.DocumentPosition, transformationEnd(t)),
+				Strategy:    t.TextTransformationStrategy,
+			}
+			if accept {
+				decision.Accepted = 1
+				result.Accepted++
+			} else {
+				decision.Rejected = 1
+				result.Rejected++
+			}
+			decisions = append(decisions, decision)
+		}
+	}
+	decide(accepted, true)
+	decide(rejected, false)
+	if len(decisions) == 0 {
+		return result, nil
+	}
+	previous := e.analysisDigest()
+	if err := e.feedback.record(decisions); err != nil {
+		return nil, err
+	}
+	// Os relatórios guardados foram pontuados com o aprendizado anterior
+	e.resultCache.invalidate(previous, e.analysisDigest())
+	return result, nil
+}
+
+// ResetFeedback esquece o aprendido para strategy, ou tudo se vazio
+func (e *Engine) ResetFeedback(strategy string) error {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return acquireErr
+	}
+	defer release()
+	if e.feedback == nil {
+		return NewError(ErrCodeInvalidArgument, "feedback file not configured", nil)
+	}
+	previous := e.analysisDigest()
+	if err := e.feedback.Reset(strategy); err != nil {
+		return err
+	}
+	e.resultCache.invalidate(previous, e.analysisDigest())
+	return nil
+}
+
+// LearnedFeedback decisões acumuladas e ajustes atuais do aprendizado da
+// instância
+func (e *Engine) LearnedFeedback() (*FeedbackSummary, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.feedback == nil {
+		return nil, NewError(ErrCodeInvalidArgument, "feedback file not configured", nil)
+	}
+	return e.feedback.Summary(), nil
+}
+
+// Feedback aprendizado da instância; nil quando não configurado
+func (e *Engine) Feedback() *FeedbackStore {
+	return e.feedback
+}
+
+// analysisDigest versão do dicionário e, se houver, do aprendizado: tudo o
+// que muda as pontuações para o mesmo conteúdo e as mesmas opções
+func (e *Engine) analysisDigest() string {
+	digest := e.currentDictionaryDigest()
+	if learned := e.feedback.Digest(); learned != "" {
+		digest += "+" + learned
+	}
+	return digest
+}
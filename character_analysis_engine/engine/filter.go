@@ -0,0 +1,228 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxLineLength tamanho máximo de linha processada de uma vez no modo
+// filtro. Linhas maiores são tratadas em pedaços, o que mantém a memória
+// limitada mas pode deixar de corrigir uma sequência cortada entre pedaços.
+const DefaultMaxLineLength = 1 << 20
+
+// FilterOptions configuração do modo filtro
+type FilterOptions struct {
+	// From charset de entrada; CharsetAuto detecta linha a linha
+	From string
+	// To charset de saída
+	To string
+	// Substitute troca bytes inválidos por U+FFFD e caracteres sem
+	// representação na saída por '?', em vez de abortar
+	Substitute bool
+	// MaxLineLength 0 usa DefaultMaxLineLength
+	MaxLineLength int
+}
+
+// LineReport anomalias e correções de uma linha. As posições são relativas
+// ao início da linha já convertida para UTF-8.
+type LineReport struct {
+	Line              int                  `json:"line"`
+	Offset            int64                `json:"offset"`
+	Encoding          string               `json:"encoding"`
+	EncodingAnomalies []EncodingAnomaly    `json:"encodingAnomalies"`
+	AppliedTransforms []TextTransformation `json:"appliedTransforms"`
+}
+
+// FilterSummary totais de uma execução do modo filtro
+type FilterSummary struct {
+	From             string        `json:"from"`
+	To               string        `json:"to"`
+	Lines            int64         `json:"lines"`
+	ChangedLines     int64         `json:"changedLines"`
+	BytesIn          int64         `json:"bytesIn"`
+	BytesOut         int64         `json:"bytesOut"`
+	Anomalies        int64         `json:"anomalies"`
+	Corrections      int64         `json:"corrections"`
+	EffectiveOptions Options       `json:"effectiveOptions"`
+	FilterDuration   time.Duration `json:"filterDuration"`
+}
+
+// Filter lê r linha a linha, corrige cada linha e grava o resultado em w no
+// charset pedido. visit (opcional) recebe as linhas com anomalias ou
+// correções assim que são processadas; nada além da linha atual fica em
+// memória.
+func (e *Engine) Filter(ctx context.Context, r io.Reader, w io.Writer, options Options, filter FilterOptions, visit func(LineReport)) (*FilterSummary, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	filter, err := filter.normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+
+	startTime := time.Now()
+	summary := &FilterSummary{From: filter.From, To: filter.To, EffectiveOptions: options}
+	reader := bufio.NewReaderSize(r, filter.MaxLineLength)
+	writer := bufio.NewWriter(w)
+	line := 1
+	var offset int64
+	var carry []byte
+	// partial indica uma linha já iniciada mas ainda sem terminador
+	partial := false
+
+	for {
+		chunk, readErr := reader.ReadSlice('\n')
+		// ReadSlice reutiliza o buffer: append sempre copia
+		piece := append(carry, chunk...)
+		carry = nil
+		if readErr == bufio.ErrBufferFull && filter.From != CharsetISO88591 && filter.From != CharsetWindows1252 {
+			// Não corta um caractere UTF-8 ao meio
+			cut := incompleteRuneStart(piece)
+			carry = append([]byte(nil), piece[cut:]...)
+			piece = piece[:cut]
+		}
+		if readErr != nil && readErr != io.EOF && readErr != bufio.ErrBufferFull {
+			return nil, NewError(ErrCodeIO, "failed to read input", map[string]interface{}{"line": line, "cause": readErr.Error()})
+		}
+
+		if len(piece) > 0 {
+			summary.BytesIn += int64(len(piece))
+			content, terminator := splitLineTerminator(piece)
+			decoded, badIndex, ok := decodeCharset(content, filter.From, filter.Substitute)
+			if !ok {
+				return nil, NewError(ErrCodeCharset, "invalid input for charset", map[string]interface{}{
+					"charset": filter.From, "line": line, "byte": fmt.Sprintf("0x%02X", content[badIndex]),
+				})
+			}
+
+			report, analyzeErr := e.analyzeText(ctx, "", decoded, lineEncoding(content, filter.From), options)
+			if analyzeErr != nil {
+				return nil, analyzeErr
+			}
+			fixed, applyErr := ApplyTransformations(decoded, report.SuggestedTransforms)
+			if applyErr != nil {
+				return nil, applyErr
+			}
+			output, badPos, ok := encodeCharset(fixed+string(terminator), filter.To, filter.Substitute)
+			if !ok {
+				bad, _ := utf8.DecodeRuneInString(fixed[badPos:])
+				return nil, NewError(ErrCodeCharset, "character cannot be represented in output charset", map[string]interface{}{
+					"charset": filter.To, "line": line, "character": string(bad),
+				})
+			}
+			if _, writeErr := writer.Write(output); writeErr != nil {
+				return nil, NewError(ErrCodeIO, "failed to write output", map[string]interface{}{"line": line, "cause": writeErr.Error()})
+			}
+
+			summary.BytesOut += int64(len(output))
+			summary.Anomalies += int64(len(report.EncodingAnomalies))
+			summary.Corrections += int64(len(report.SuggestedTransforms))
+			if len(report.SuggestedTransforms) > 0 {
+				summary.ChangedLines++
+			}
+			if visit != nil && (len(report.EncodingAnomalies) > 0 || len(report.SuggestedTransforms) > 0) {
+				visit(LineReport{
+					Line:              line,
+					Offset:            offset,
+					Encoding:          report.SourceCharacterSet,
+					EncodingAnomalies: report.EncodingAnomalies,
+					AppliedTransforms: report.SuggestedTransforms,
+				})
+			}
+			offset += int64(len(decoded) + len(terminator))
+			partial = len(terminator) == 0
+			if !partial {
+				summary.Lines++
+				line++
+			}
+		}
+
+		if readErr == io.EOF {
+			if len(carry) > 0 {
+				continue
+			}
+			break
+		}
+	}
+
+	if partial {
+		// Última linha sem terminador
+		summary.Lines++
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to write output", map[string]interface{}{"cause": err.Error()})
+	}
+	summary.FilterDuration = time.Since(startTime)
+	return summary, nil
+}
+
+// normalize valida os charsets e aplica os valores padrão
+func (f FilterOptions) normalize() (FilterOptions, error) {
+	if f.From == "" {
+		f.From = CharsetAuto
+	}
+	if f.To == "" {
+		f.To = CharsetUTF8
+	}
+	var err error
+	if f.From, err = NormalizeCharset(f.From); err != nil {
+		return f, err
+	}
+	if f.To, err = NormalizeCharset(f.To); err != nil {
+		return f, err
+	}
+	if f.To == CharsetAuto {
+		return f, NewError(ErrCodeInvalidArgument, "output charset must be explicit", map[string]interface{}{"charset": f.To})
+	}
+	if f.MaxLineLength < 0 {
+		return f, NewError(ErrCodeInvalidArgument, "max line length must not be negative", map[string]interface{}{"maxLineLength": f.MaxLineLength})
+	}
+	if f.MaxLineLength == 0 {
+		f.MaxLineLength = DefaultMaxLineLength
+	}
+	return f, nil
+}
+
+// splitLineTerminator separa o conteúdo do "\n" ou "\r\n" final
+func splitLineTerminator(piece []byte) ([]byte, []byte) {
+	if bytes.HasSuffix(piece, []byte("\r\n")) {
+		return piece[:len(piece)-2], piece[len(piece)-2:]
+	}
+	if bytes.HasSuffix(piece, []byte("\n")) {
+		return piece[:len(piece)-1], piece[len(piece)-1:]
+	}
+	return piece, nil
+}
+
+// incompleteRuneStart retorna onde começa um caractere UTF-8 incompleto no
+// fim de data, ou len(data) se não houver
+func incompleteRuneStart(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// lineEncoding nome do encoding reportado para a linha
+func lineEncoding(content []byte, from string) string {
+	if from == CharsetAuto {
+		return detectEncoding(content)
+	}
+	return from
+}
@@ -0,0 +1,346 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// scanIndexVersion versão do formato do arquivo de índice
+const scanIndexVersion = 1
+
+// Situação de um arquivo em relação ao índice
+const (
+	ChangeAdded     = "added"
+	ChangeModified  = "modified"
+	ChangeUnchanged = "unchanged"
+	ChangeRemoved   = "removed"
+)
+
+// Códigos de aviso do índice
+const (
+	warningIndexCorrupted = "index_corrupted"
+	warningIndexReset     = "index_reset"
+)
+
+// IndexEntry estado de um arquivo na última varredura incremental
+type IndexEntry struct {
+	Size    int64         `json:"size"`
+	ModTime int64         `json:"modTime"`
+	Hash    string        `json:"hash"`
+	Summary IndexedReport `json:"summary"`
+
+	// Config Fingerprint da configuração de projeto na última análise; vazio
+	// sem projectConfig. Se mudar, o arquivo é reanalisado mesmo inalterado.
+	Config string `json:"config,omitempty"`
+}
+
+// IndexedReport resumo do último relatório de um arquivo
+type IndexedReport struct {
+	Status        string  `json:"status"`
+	SkipReason    string  `json:"skipReason,omitempty"`
+	Encoding      string  `json:"encoding,omitempty"`
+	AccuracyScore float64 `json:"accuracyScore"`
+	AnomalyCount  int     `json:"anomalyCount"`
+	Corrections   int     `json:"corrections"`
+}
+
+// ScanIndex índice persistente da varredura incremental. Fingerprint
+// identifica as opções e o dicionário usados: se mudarem, todos os arquivos
+// são reanalisados.
+type ScanIndex struct {
+	Version     int                    `json:"version"`
+	Fingerprint string                 `json:"fingerprint"`
+	Entries     map[string]*IndexEntry `json:"entries"`
+
+	path string
+	lock sync.Mutex
+}
+
+// LoadScanIndex lê o índice em path. Um índice inexistente começa vazio. Um
+// índice ilegível ou de outra versão é renomeado para path+".corrupt" e
+// substituído por um vazio, com um aviso: tudo é reanalisado, nada é perdido.
+func LoadScanIndex(path string) (*ScanIndex, []Warning, error) {
+	index := &ScanIndex{Version: scanIndexVersion, Entries: map[string]*IndexEntry{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil, nil
+	}
+	if err != nil {
+		return nil, nil, NewError(ErrCodeIO, "failed to read scan index", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+
+	var stored ScanIndex
+	decodeErr := json.Unmarshal(data, &stored)
+	if decodeErr == nil && stored.Version != scanIndexVersion {
+		decodeErr = errors.New("unsupported index version")
+	}
+	if decodeErr != nil {
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			return nil, nil, NewError(ErrCodeIO, "failed to move corrupted scan index aside", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+		return index, []Warning{{
+			Code:    warningIndexCorrupted,
+			Message: "scan index is unreadable (" + decodeErr.Error() + "); moved to " + path + ".corrupt and rebuilding",
+		}}, nil
+	}
+	for entryPath, entry := range stored.Entries {
+		if entry != nil {
+			index.Entries[entryPath] = entry
+		}
+	}
+	index.Fingerprint = stored.Fingerprint
+	return index, nil, nil
+}
+
+// Save grava o índice de forma atômica
+func (x *ScanIndex) Save() error {
+	x.lock.Lock()
+	data, err := json.Marshal(x)
+	x.lock.Unlock()
+	if err != nil {
+		return NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+	if err := writeFileAtomic(x.path, data); err != nil {
+		return NewError(ErrCodeIO, "failed to write scan index", map[string]interface{}{"path": x.path, "cause": err.Error()})
+	}
+	return nil
+}
+
+// Path arquivo do índice
+func (x *ScanIndex) Path() string {
+	return x.path
+}
+
+// Lookup devolve uma cópia da entrada de path
+func (x *ScanIndex) Lookup(path string) (IndexEntry, bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	entry, ok := x.Entries[path]
+	if !ok {
+		return IndexEntry{}, false
+	}
+	return *entry, true
+}
+
+func (x *ScanIndex) put(path string, entry IndexEntry) {
+	x.lock.Lock()
+	x.Entries[path] = &entry
+	x.lock.Unlock()
+}
+
+func (x *ScanIndex) remove(path string) {
+	x.lock.Lock()
+	delete(x.Entries, path)
+	x.lock.Unlock()
+}
+
+// IncrementalResult diferenças encontradas por AnalyzeDirectoryIncremental
+type IncrementalResult struct {
+	Root      string        `json:"root"`
+	Full      bool          `json:"full"`
+	Added     int           `json:"added"`
+	Modified  int           `json:"modified"`
+	Unchanged int           `json:"unchanged"`
+	Removed   []string      `json:"removed"`
+	Skipped   []SkippedFile `json:"skipped"`
+	Warnings  []Warning     `json:"warnings,omitempty"`
+}
+
+// AnalyzeDirectoryIncremental varre root como AnalyzeDirectory, mas consulta o
+// índice: arquivos com mesmo tamanho e data de modificação não são lidos, e
+// arquivos com mesmo hash não são reanalisados. visit só é chamado para os
+// arquivos novos ou alterados. Entradas de arquivos sob root que não existem
+// mais (ou que os filtros agora excluem) são removidas do índice. Com full,
+// ou quando opções ou dicionário mudaram, tudo é reanalisado.
+// O índice é atualizado em memória; o chamador decide quando chamar Save.
+func (e *Engine) AnalyzeDirectoryIncremental(ctx context.Context, root string, scan ScanOptions, options Options, index *ScanIndex, full bool, visit func(path, change string, report *Report, err error)) (*IncrementalResult, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+
+	// Caminhos absolutos: o índice pode ser consultado de qualquer diretório
+	absRoot, absErr := filepath.Abs(root)
+	if absErr != nil {
+		return nil, NewError(ErrCodeInvalidPath, "failed to resolve directory", map[string]interface{}{"path": root, "cause": absErr.Error()})
+	}
+	root = absRoot
+
+	// O próprio índice pode estar dentro de root e não deve ser analisado
+	if absIndex, err := filepath.Abs(index.path); err == nil {
+		accept := scan.Accept
+		scan.Accept = func(path string) string {
+			if path == absIndex || path == absIndex+".corrupt" {
+				return SkipScanIndex
+			}
+			if accept != nil {
+				return accept(path)
+			}
+			return ""
+		}
+	}
+
+	result := &IncrementalResult{Root: root, Removed: []string{}, Skipped: []SkippedFile{}}
+	fingerprint := analysisFingerprint(options, e.analysisDigest())
+	index.lock.Lock()
+	if index.Fingerprint != fingerprint && len(index.Entries) > 0 && !full {
+		full = true
+		result.Warnings = append(result.Warnings, Warning{
+			Code:    warningIndexReset,
+			Message: "analysis options or dictionary changed since the last scan; analysing every file",
+		})
+	}
+	index.Fingerprint = fingerprint
+	index.lock.Unlock()
+	result.Full = full
+
+	e.totalFiles.Store(0)
+	e.processing.Store(0)
+
+	var batch sync.WaitGroup
+	var countsLock sync.Mutex
+	count := func(change string) {
+		countsLock.Lock()
+		defer countsLock.Unlock()
+		switch change {
+		case ChangeAdded:
+			result.Added++
+		case ChangeModified:
+			result.Modified++
+		case ChangeUnchanged:
+			result.Unchanged++
+		}
+	}
+
+	seen := map[string]bool{}
+	err := walkDirectory(ctx, root, scan, func(path string) error {
+		seen[path] = true
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			result.Skipped = append(result.Skipped, SkippedFile{Path: path, Reason: SkipUnreadable, Detail: statErr.Error()})
+			index.remove(path)
+			return nil
+		}
+		var previous *IndexEntry
+		if entry, known := index.Lookup(path); known {
+			// Um erro aqui reaparece na análise, que resolve a configuração de novo
+			if !full && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() && entry.Config == e.projectFingerprintFor(path, options) {
+				count(ChangeUnchanged)
+				return nil
+			}
+			previous = &entry
+		}
+
+		e.totalFiles.Add(1)
+		return e.submitTask(ctx, &batch, func(taskCtx context.Context) {
+			change, report, err := e.analyzeIndexed(taskCtx, path, info, options, index, previous, full)
+			count(change)
+			if change != ChangeUnchanged {
+				visit(path, change, report, err)
+			}
+		})
+	}, func(skipped SkippedFile) {
+		result.Skipped = append(result.Skipped, skipped)
+	})
+	batch.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	// Só depois de uma varredura completa: remove o que sumiu de root
+	prefix := strings.TrimSuffix(root, string(filepath.Separator)) + string(filepath.Separator)
+	index.lock.Lock()
+	for entryPath := range index.Entries {
+		if strings.HasPrefix(entryPath, prefix) && !seen[entryPath] {
+			delete(index.Entries, entryPath)
+			result.Removed = append(result.Removed, entryPath)
+		}
+	}
+	index.lock.Unlock()
+	sort.Strings(result.Removed)
+	return result, nil
+}
+
+// analyzeIndexed lê o arquivo, compara o hash com a entrada anterior (nil se
+// o arquivo é novo) e só analisa se o conteúdo mudou. Erros e timeouts tiram
+// o arquivo do índice para que a próxima varredura tente de novo.
+func (e *Engine) analyzeIndexed(ctx context.Context, path string, info os.FileInfo, options Options, index *ScanIndex, previous *IndexEntry, full bool) (string, *Report, error) {
+	change := ChangeAdded
+	if previous != nil {
+		change = ChangeModified
+	}
+
+	entry := IndexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	options, effective, err := e.OptionsForPath(path, options)
+	if err != nil {
+		index.remove(path)
+		return change, nil, err
+	}
+	if effective != nil {
+		entry.Config = effective.Fingerprint
+	}
+	var report *Report
+	if effective != nil && effective.Ignored {
+		report = effective.IgnoredReport()
+	} else if options.MaxFileSize > 0 && info.Size() > options.MaxFileSize {
+		report = limitSkippedReport(path, skipReasonTooLarge, fileSizeWarning(options), options)
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			index.remove(path)
+			return change, nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+		entry.Hash = hashContent(data)
+		if previous != nil && !full && previous.Hash == entry.Hash && previous.Config == entry.Config {
+			// Só a data mudou: mantém o resumo anterior
+			entry.Summary = previous.Summary
+			index.put(path, entry)
+			return ChangeUnchanged, nil, nil
+		}
+		report, err = e.analyzeContent(ctx, path, data, options)
+		if err != nil {
+			index.remove(path)
+			return change, nil, err
+		}
+		withProjectWarnings(report, effective)
+	}
+
+	if report.SkipReason == skipReasonTimeout {
+		index.remove(path)
+		return change, report, nil
+	}
+	entry.Summary = indexedReport(report)
+	index.put(path, entry)
+	return change, report, nil
+}
+
+// indexedReport resumo de um relatório para o índice e para o diário
+func indexedReport(report *Report) IndexedReport {
+	return IndexedReport{
+		Status:        report.Status,
+		SkipReason:    report.SkipReason,
+		Encoding:      report.SourceCharacterSet,
+		AccuracyScore: report.AccuracyScore,
+		AnomalyCount:  report.AnomalyCount,
+		Corrections:   len(report.SuggestedTransforms),
+	}
+}
+
+// hashContent sha256 do conteúdo em hexadecimal
+func hashContent(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,647 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registros do diário de um job. Cada registro é uma linha JSON gravada e
+// sincronizada antes do passo seguinte, então o diário sempre descreve o que
+// já aconteceu no disco.
+const (
+	journalStart       = "start"
+	journalDone        = "done"
+	journalFailed      = "failed"
+	journalFixBegin    = "fix_begin"
+	journalFixRollback = "fix_rollback"
+	journalFinish      = "finish"
+	journalAbort       = "abort"
+)
+
+// Estados de um job
+const (
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobAborted  = "aborted"
+)
+
+// Situação de uma correção interrompida no meio (fix_begin sem done)
+const (
+	FixStateApplied    = "applied"
+	FixStateNotApplied = "not_applied"
+	FixStateConflict   = "conflict"
+)
+
+// Destino de uma correção interrompida após RunJob ou Rollback
+const (
+	FixResolutionCompleted  = "completed"
+	FixResolutionRetried    = "retried"
+	FixResolutionRolledBack = "rolled_back"
+	FixResolutionConflict   = "conflict"
+)
+
+// recordIDPattern ids aceitos em OpenJob e UndoTransaction; impede caminhos
+// fora do diretório
+var recordIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// journalRecord linha do diário
+type journalRecord struct {
+	Type         string         `json:"type"`
+	Time         time.Time      `json:"time"`
+	JobID        string         `json:"jobId,omitempty"`
+	Paths        []string       `json:"paths,omitempty"`
+	Options      *Options       `json:"options,omitempty"`
+	Explicit     []string       `json:"explicitOptions,omitempty"`
+	Apply        bool           `json:"apply,omitempty"`
+	Path         string         `json:"path,omitempty"`
+	Result       *IndexedReport `json:"result,omitempty"`
+	Error        *Error         `json:"error,omitempty"`
+	OriginalHash string         `json:"originalHash,omitempty"`
+	FixedHash    string         `json:"fixedHash,omitempty"`
+	Backup       string         `json:"backup,omitempty"`
+}
+
+// PendingFix correção cujo início está no diário mas o fim não
+type PendingFix struct {
+	Path         string `json:"path"`
+	OriginalHash string `json:"originalHash"`
+	FixedHash    string `json:"fixedHash"`
+	Backup       string `json:"backup"`
+	State        string `json:"state"`
+	Resolution   string `json:"resolution,omitempty"`
+}
+
+// JobInfo situação de um job reconstruída a partir do diário
+type JobInfo struct {
+	ID           string       `json:"jobId"`
+	Created      time.Time    `json:"created"`
+	Apply        bool         `json:"apply"`
+	State        string       `json:"state"`
+	Total        int          `json:"total"`
+	Completed    int          `json:"completed"`
+	Failed       int          `json:"failed"`
+	PendingFixes []PendingFix `json:"pendingFixes,omitempty"`
+}
+
+// Job lote retomável. Os caminhos concluídos e seus resultados ficam no
+// diário <dir>/<id>.journal; com apply, o original de cada arquivo corrigido
+// é guardado em <dir>/<id>.backups antes da escrita.
+type Job struct {
+	dir     string
+	id      string
+	created time.Time
+	paths   []string
+	options Options
+	apply   bool
+
+	lock      sync.Mutex
+	journal   *os.File
+	state     string
+	completed map[string]IndexedReport
+	failed    map[string]*Error
+	pending   map[string]journalRecord
+	resolved  []PendingFix
+}
+
+// CreateJob registra um novo job em dir com os caminhos e opções dados.
+// Com apply as correções sugeridas são gravadas nos arquivos.
+func CreateJob(dir string, paths []string, options Options, apply bool) (*Job, error) {
+	if dir == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "job directory not configured", nil)
+	}
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to create job directory", map[string]interface{}{"dir": dir, "cause": err.Error()})
+	}
+	created := time.Now().UTC()
+	id, err := newRecordID(created)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		dir:       dir,
+		id:        id,
+		created:   created,
+		paths:     append([]string{}, paths...),
+		options:   options,
+		apply:     apply,
+		state:     JobRunning,
+		completed: map[string]IndexedReport{},
+		failed:    map[string]*Error{},
+		pending:   map[string]journalRecord{},
+	}
+
+	file, err := os.OpenFile(job.journalPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to create job journal", map[string]interface{}{"path": job.journalPath(), "cause": err.Error()})
+	}
+	job.journal = file
+	if err := job.append(journalRecord{Type: journalStart, Time: created, JobID: job.id, Paths: job.paths, Options: &job.options, Explicit: job.options.explicitKeys(), Apply: apply}); err != nil {
+		job.Close()
+		return nil, err
+	}
+	return job, nil
+}
+
+// newRecordID id de job ou transação: data e hora UTC e um sufixo aleatório
+func newRecordID(created time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", NewError(ErrCodeInternal, "failed to generate id", map[string]interface{}{"cause": err.Error()})
+	}
+	return created.Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// OpenJob reabre um job pelo id reconstruindo seu estado a partir do diário.
+// Uma última linha incompleta (queda durante a escrita) é descartada.
+func OpenJob(dir, id string) (*Job, error) {
+	if !recordIDPattern.MatchString(id) {
+		return nil, NewError(ErrCodeInvalidArgument, "invalid job id", map[string]interface{}{"jobId": id})
+	}
+	job := &Job{
+		dir:       dir,
+		id:        id,
+		state:     JobRunning,
+		completed: map[string]IndexedReport{},
+		failed:    map[string]*Error{},
+		pending:   map[string]journalRecord{},
+	}
+	data, err := os.ReadFile(job.journalPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, NewError(ErrCodeInvalidArgument, "unknown job", map[string]interface{}{"jobId": id})
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read job journal", map[string]interface{}{"path": job.journalPath(), "cause": err.Error()})
+	}
+
+	valid, err := job.replay(data)
+	if err != nil {
+		return nil, err
+	}
+	file, openErr := os.OpenFile(job.journalPath(), os.O_WRONLY|os.O_APPEND, 0o644)
+	if openErr == nil && valid < len(data) {
+		openErr = file.Truncate(int64(valid))
+	}
+	if openErr != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, NewError(ErrCodeIO, "failed to open job journal", map[string]interface{}{"path": job.journalPath(), "cause": openErr.Error()})
+	}
+	job.journal = file
+	return job, nil
+}
+
+// replay aplica os registros do diário e devolve quantos bytes são válidos
+func (j *Job) replay(data []byte) (int, error) {
+	valid := 0
+	started := false
+	for valid < len(data) {
+		end := bytes.IndexByte(data[valid:], '\n')
+		if end < 0 {
+			// Linha final sem quebra: escrita interrompida
+			break
+		}
+		line := data[valid : valid+end]
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil || (!started && record.Type != journalStart) {
+			if valid+end+1 == len(data) {
+				break
+			}
+			return 0, NewError(ErrCodeIO, "job journal is corrupted", map[string]interface{}{"path": j.journalPath(), "offset": valid})
+		}
+		started = true
+		j.applyRecord(record)
+		valid += end + 1
+	}
+	if !started {
+		return 0, NewError(ErrCodeIO, "job journal is corrupted", map[string]interface{}{"path": j.journalPath(), "offset": 0})
+	}
+	return valid, nil
+}
+
+// applyRecord atualiza o estado em memória com um registro
+func (j *Job) applyRecord(record journalRecord) {
+	switch record.Type {
+	case journalStart:
+		j.created = record.Time
+		j.paths = record.Paths
+		if record.Options != nil {
+			j.options = record.Options.withExplicit(record.Explicit)
+		}
+		j.apply = record.Apply
+	case journalDone:
+		delete(j.pending, record.Path)
+		if record.Result != nil {
+			j.completed[record.Path] = *record.Result
+		}
+	case journalFailed:
+		delete(j.pending, record.Path)
+		j.failed[record.Path] = record.Error
+	case journalFixBegin:
+		j.pending[record.Path] = record
+	case journalFixRollback:
+		delete(j.pending, record.Path)
+	case journalFinish:
+		j.state = JobFinished
+	case journalAbort:
+		j.state = JobAborted
+	}
+}
+
+// append grava e sincroniza um registro e o aplica ao estado
+func (j *Job) append(record journalRecord) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if _, err := j.journal.Write(append(line, '\n')); err != nil {
+		return NewError(ErrCodeIO, "failed to write job journal", map[string]interface{}{"path": j.journalPath(), "cause": err.Error()})
+	}
+	if err := j.journal.Sync(); err != nil {
+		return NewError(ErrCodeIO, "failed to sync job journal", map[string]interface{}{"path": j.journalPath(), "cause": err.Error()})
+	}
+	j.applyRecord(record)
+	return nil
+}
+
+// ID identificador do job
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Close fecha o diário; o job pode ser reaberto com OpenJob
+func (j *Job) Close() error {
+	if j.journal == nil {
+		return nil
+	}
+	err := j.journal.Close()
+	j.journal = nil
+	return err
+}
+
+// Info situação atual do job. As correções pendentes são inspecionadas no disco.
+func (j *Job) Info() JobInfo {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	info := JobInfo{
+		ID:        j.id,
+		Created:   j.created,
+		Apply:     j.apply,
+		State:     j.state,
+		Total:     len(j.paths),
+		Completed: len(j.completed),
+		Failed:    len(j.failed),
+	}
+	info.PendingFixes = append(info.PendingFixes, j.resolved...)
+	for _, record := range j.pending {
+		info.PendingFixes = append(info.PendingFixes, inspectPendingFix(record))
+	}
+	sort.Slice(info.PendingFixes, func(a, b int) bool { return info.PendingFixes[a].Path < info.PendingFixes[b].Path })
+	return info
+}
+
+// currentState estado do job lido sob a trava
+func (j *Job) currentState() string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.state
+}
+
+func (j *Job) journalPath() string {
+	return filepath.Join(j.dir, j.id+".journal")
+}
+
+func (j *Job) backupPath(hash string) string {
+	return filepath.Join(j.dir, j.id+".backups", hash)
+}
+
+// remaining caminhos ainda sem resultado, na ordem original
+func (j *Job) remaining() []string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	var paths []string
+	for _, path := range j.paths {
+		if _, done := j.completed[path]; done {
+			continue
+		}
+		if _, failed := j.failed[path]; failed {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// pendingFixes cópia dos registros fix_begin sem desfecho
+func (j *Job) pendingFixes() []journalRecord {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	records := make([]journalRecord, 0, len(j.pending))
+	for _, record := range j.pending {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(a, b int) bool { return records[a].Path < records[b].Path })
+	return records
+}
+
+// inspectPendingFix compara o arquivo atual com os hashes do fix_begin
+func inspectPendingFix(record journalRecord) PendingFix {
+	fix := PendingFix{
+		Path:         record.Path,
+		OriginalHash: record.OriginalHash,
+		FixedHash:    record.FixedHash,
+		Backup:       record.Backup,
+		State:        FixStateConflict,
+	}
+	data, err := os.ReadFile(record.Path)
+	if err != nil {
+		return fix
+	}
+	switch hashContent(data) {
+	case record.FixedHash:
+		fix.State = FixStateApplied
+	case record.OriginalHash:
+		fix.State = FixStateNotApplied
+	}
+	return fix
+}
+
+// ListJobs lista os jobs de dir, mais recentes primeiro
+func ListJobs(dir string) ([]JobInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []JobInfo{}, nil
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read job directory", map[string]interface{}{"dir": dir, "cause": err.Error()})
+	}
+	jobs := []JobInfo{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".journal")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		job, err := OpenJob(dir, id)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job.Info())
+		job.Close()
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Created.After(jobs[b].Created) })
+	return jobs, nil
+}
+
+// ListJobs lista os jobs do diretório configurado na instância
+func (e *Engine) ListJobs() ([]JobInfo, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.config.JobDirectory == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "job directory not configured", nil)
+	}
+	return ListJobs(e.config.JobDirectory)
+}
+
+// RunJob processa os caminhos ainda sem resultado no pool da instância e
+// chama visit para cada um. Correções interrompidas por uma queda anterior
+// são concluídas primeiro: as já gravadas são confirmadas, as que não
+// chegaram ao disco são refeitas e as alteradas por fora ficam como falha.
+// Se ctx for cancelado o job continua retomável.
+func (e *Engine) RunJob(ctx context.Context, job *Job, visit func(path string, report *Report, err error)) (JobInfo, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return JobInfo{}, acquireErr
+	}
+	defer release()
+
+	if state := job.currentState(); state != JobRunning {
+		return job.Info(), NewError(ErrCodeInvalidArgument, "job is already "+state, map[string]interface{}{"jobId": job.id})
+	}
+
+	for _, record := range job.pendingFixes() {
+		fix := inspectPendingFix(record)
+		var err error
+		switch fix.State {
+		case FixStateApplied:
+			fix.Resolution = FixResolutionCompleted
+			err = job.append(journalRecord{Type: journalDone, Path: record.Path, Result: record.Result})
+		case FixStateNotApplied:
+			fix.Resolution = FixResolutionRetried
+			err = job.append(journalRecord{Type: journalFixRollback, Path: record.Path})
+		default:
+			fix.Resolution = FixResolutionConflict
+			err = job.append(journalRecord{Type: journalFailed, Path: record.Path, Error: errFixConflict(record.Path)})
+		}
+		if err != nil {
+			return job.Info(), err
+		}
+		job.lock.Lock()
+		job.resolved = append(job.resolved, fix)
+		job.lock.Unlock()
+	}
+
+	paths := job.remaining()
+	e.totalFiles.Store(int64(len(paths)))
+	e.processing.Store(0)
+
+	var batch sync.WaitGroup
+	var journalErr error
+	var journalErrLock sync.Mutex
+	for _, path := range paths {
+		path := path
+		submitErr := e.submitTask(ctx, &batch, func(taskCtx context.Context) {
+			if taskCtx.Err() != nil {
+				return
+			}
+			report, err := e.runJobPath(taskCtx, job, path)
+			// Cancelamento não é resultado: o caminho fica para a retomada
+			if err != nil && AsError(err, ErrCodeInternal).Code == ErrCodeCancelled {
+				return
+			}
+			var record journalRecord
+			if err != nil {
+				record = journalRecord{Type: journalFailed, Path: path, Error: AsError(err, ErrCodeInternal)}
+			} else {
+				result := indexedReport(report)
+				record = journalRecord{Type: journalDone, Path: path, Result: &result}
+			}
+			if appendErr := job.append(record); appendErr != nil {
+				journalErrLock.Lock()
+				journalErr = appendErr
+				journalErrLock.Unlock()
+			}
+			visit(path, report, err)
+		})
+		if submitErr != nil {
+			batch.Wait()
+			return job.Info(), submitErr
+		}
+	}
+	batch.Wait()
+
+	if journalErr != nil {
+		return job.Info(), journalErr
+	}
+	if ctx.Err() == nil && len(job.remaining()) == 0 {
+		if err := job.append(journalRecord{Type: journalFinish}); err != nil {
+			return job.Info(), err
+		}
+	}
+	return job.Info(), nil
+}
+
+// runJobPath analisa um caminho e, em jobs com apply, grava a correção
+// registrando fix_begin antes de tocar no arquivo
+func (e *Engine) runJobPath(ctx context.Context, job *Job, path string) (*Report, error) {
+	if !job.apply {
+		return e.analyzePath(ctx, path, job.options)
+	}
+
+	options, effective, err := e.OptionsForPath(path, job.options)
+	if err != nil {
+		return nil, err
+	}
+	if effective != nil && effective.Ignored {
+		return effective.IgnoredReport(), nil
+	}
+	if options.MaxFileSize > 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() > options.MaxFileSize {
+			return limitSkippedReport(path, skipReasonTooLarge, fileSizeWarning(options), options), nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	fixed, report, err := e.fixContent(ctx, data, options)
+	if err != nil {
+		// fixContent aplica o próprio timeout_ms e o devolve como limit_exceeded
+		if AsError(err, ErrCodeInternal).Code == ErrCodeLimitExceeded {
+			return limitSkippedReport(path, skipReasonTimeout, timeoutWarning(options), options), nil
+		}
+		return nil, err
+	}
+	report.DocumentPath = path
+	withProjectWarnings(report, effective)
+	if report.Status != StatusAnalyzed || len(report.SuggestedTransforms) == 0 || fixed == string(data) {
+		return report, nil
+	}
+
+	// O original vai para o diretório do job antes de qualquer escrita
+	originalHash := hashContent(data)
+	backup := job.backupPath(originalHash)
+	if _, err := os.Stat(backup); err != nil {
+		if err := writeFileAtomic(backup, data); err != nil {
+			return nil, NewError(ErrCodeIO, "failed to write job backup", map[string]interface{}{"path": path, "backup": backup, "cause": err.Error()})
+		}
+	}
+	result := indexedReport(report)
+	if err := job.append(journalRecord{
+		Type:         journalFixBegin,
+		Path:         path,
+		Result:       &result,
+		OriginalHash: originalHash,
+		FixedHash:    hashContent([]byte(fixed)),
+		Backup:       backup,
+	}); err != nil {
+		return nil, err
+	}
+	if err := e.applyFixed(AuditJob, path, originalHash, fixed, report.SuggestedTransforms, options); err != nil {
+		// A troca é atômica e recusada se o arquivo mudou depois da leitura:
+		// ele continua como estava
+		if rollbackErr := job.append(journalRecord{Type: journalFixRollback, Path: path}); rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// Rollback abandona o job: correções interrompidas já gravadas voltam ao
+// original guardado no diretório do job, as que não chegaram ao disco são
+// descartadas e o job fica aborted. Correções concluídas não são desfeitas.
+// Arquivos alterados por fora não são tocados e aparecem como conflict;
+// enquanto houver conflitos o job continua como está, para que o rollback
+// seja repetido depois de resolvê-los. As restaurações são registradas em
+// audit, se não for nil.
+func (j *Job) Rollback(audit *AuditLog) (JobInfo, error) {
+	if state := j.currentState(); state != JobRunning {
+		return j.Info(), NewError(ErrCodeInvalidArgument, "job is already "+state, map[string]interface{}{"jobId": j.id})
+	}
+	var conflicts []PendingFix
+	for _, record := range j.pendingFixes() {
+		fix := inspectPendingFix(record)
+		switch fix.State {
+		case FixStateApplied:
+			original, err := os.ReadFile(record.Backup)
+			if err != nil || hashContent(original) != record.OriginalHash {
+				fix.Resolution = FixResolutionConflict
+				conflicts = append(conflicts, fix)
+				continue
+			}
+			current, err := os.ReadFile(record.Path)
+			if err != nil {
+				return j.Info(), NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": record.Path, "cause": err.Error()})
+			}
+			if err := writeRecorded(audit, AuditRollback, record.Path, current, string(original), nil, nil, ""); err != nil {
+				return j.Info(), err
+			}
+		case FixStateNotApplied:
+		default:
+			fix.Resolution = FixResolutionConflict
+			conflicts = append(conflicts, fix)
+			continue
+		}
+		if err := j.append(journalRecord{Type: journalFixRollback, Path: record.Path}); err != nil {
+			return j.Info(), err
+		}
+		fix.Resolution = FixResolutionRolledBack
+		j.lock.Lock()
+		j.resolved = append(j.resolved, fix)
+		j.lock.Unlock()
+	}
+	if len(conflicts) > 0 {
+		return j.Info(), NewError(ErrCodeIO, "some interrupted fixes could not be rolled back", map[string]interface{}{"jobId": j.id, "conflicts": conflicts})
+	}
+	if err := j.append(journalRecord{Type: journalAbort}); err != nil {
+		return j.Info(), err
+	}
+	return j.Info(), nil
+}
+
+// RollbackJob como Rollback, registrando as restaurações no log de
+// auditoria da instância. Falha com engine_not_running depois de Shutdown.
+func (e *Engine) RollbackJob(job *Job) (JobInfo, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return JobInfo{}, acquireErr
+	}
+	defer release()
+	return job.Rollback(e.audit)
+}
+
+func errFixConflict(path string) *Error {
+	return NewError(ErrCodeIO, "document changed since the interrupted fix; left untouched", map[string]interface{}{"path": path, "state": FixStateConflict})
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"time"
+)
+
+// Estados do ciclo de vida de uma instância. Uma instância nasce em
+// running, passa por stopping enquanto drena o trabalho e termina em stopped;
+// para voltar a processar é preciso criar (ou reinicializar) outra instância.
+const (
+	engineStateRunning int32 = iota + 1
+	engineStateStopping
+	engineStateStopped
+)
+
+var engineStateNames = map[int32]string{
+	engineStateRunning:  "running",
+	engineStateStopping: "stopping",
+	engineStateStopped:  "stopped",
+}
+
+// DefaultShutdownTimeout tempo máximo de drenagem sugerido para Shutdown
+const DefaultShutdownTimeout = 30 * time.Second
+
+func errNotRunning() *Error {
+	return NewError(ErrCodeNotRunning, "engine is not running; re-initialise it before issuing new calls", nil)
+}
+
+func errCancelled(path string, cause error) *Error {
+	return NewError(ErrCodeCancelled, "analysis cancelled", map[string]interface{}{"path": path, "cause": cause.Error()})
+}
+
+// IsRunning informa se a instância aceita novas chamadas
+func (e *Engine) IsRunning() bool {
+	e.lifecycleLock.RLock()
+	defer e.lifecycleLock.RUnlock()
+	return e.state == engineStateRunning
+}
+
+// State retorna o nome do estado atual do ciclo de vida
+func (e *Engine) State() string {
+	e.lifecycleLock.RLock()
+	defer e.lifecycleLock.RUnlock()
+	return engineStateNames[e.state]
+}
+
+// acquire registra uma chamada em andamento. Falha com engine_not_running se
+// a instância já começou a encerrar; a função devolvida deve ser chamada ao fim.
+func (e *Engine) acquire() (func(), *Error) {
+	e.lifecycleLock.RLock()
+	defer e.lifecycleLock.RUnlock()
+	if e.state != engineStateRunning {
+		return nil, errNotRunning()
+	}
+	e.inFlight.Add(1)
+	return e.inFlight.Done, nil
+}
+
+// Shutdown recusa novas chamadas, aguarda as chamadas em andamento por até
+// timeout, cancela o que restar e libera os dicionários. Retorna false quando
+// houve cancelamento. Chamadas repetidas são inofensivas.
+func (e *Engine) Shutdown(timeout time.Duration) bool {
+	e.lifecycleLock.Lock()
+	if e.state != engineStateRunning {
+		e.lifecycleLock.Unlock()
+		return true
+	}
+	e.state = engineStateStopping
+	e.lifecycleLock.Unlock()
+
+	drained := waitWithTimeout(&e.inFlight, timeout)
+	if !drained {
+		e.cancelRun()
+	}
+	e.concurrentProcessorPool.Stop(0)
+	e.inFlight.Wait()
+	e.cancelRun()
+
+	e.releaseDictionary()
+
+	e.lifecycleLock.Lock()
+	e.state = engineStateStopped
+	e.lifecycleLock.Unlock()
+	return drained
+}
+
+// releaseDictionary descarta as estruturas do dicionário para liberar memória
+func (e *Engine) releaseDictionary() {
+	e.dictionaryLock.Lock()
+	defer e.dictionaryLock.Unlock()
+	e.dictTrie = nil
+	e.dictBloom = nil
+	e.dictCache = nil
+	e.ngramModel = nil
+	e.resultCache.purge()
+}
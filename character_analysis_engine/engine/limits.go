@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Códigos dos avisos de limite atingido
+const (
+	warningLimitFileSize  = "limit_max_file_size"
+	warningLimitAnomalies = "limit_max_anomalies"
+	warningLimitTimeout   = "limit_timeout"
+)
+
+// Motivos de documentos não analisados por limite
+const (
+	skipReasonTooLarge = "too_large"
+	skipReasonTimeout  = "timeout"
+)
+
+// errAnalysisTimeout causa do cancelamento quando timeout_ms expira
+var errAnalysisTimeout = errors.New("analysis timeout")
+
+// cancelCheckInterval iterações entre consultas ao contexto nos laços longos
+const cancelCheckInterval = 4096
+
+// cancelCheck consulta ctx.Err() só a cada cancelCheckInterval chamadas, para
+// que os laços de detecção possam ser interrompidos sem custo perceptível
+type cancelCheck struct {
+	ctx   context.Context
+	calls int
+	done  bool
+}
+
+func newCancelCheck(ctx context.Context) *cancelCheck {
+	return &cancelCheck{ctx: ctx}
+}
+
+// cancelled informa se a análise deve ser interrompida
+func (c *cancelCheck) cancelled() bool {
+	if c.done {
+		return true
+	}
+	c.calls++
+	if c.calls%cancelCheckInterval == 0 && c.ctx.Err() != nil {
+		c.done = true
+	}
+	return c.done
+}
+
+// withAnalysisTimeout aplica timeout_ms ao contexto de um documento
+func withAnalysisTimeout(ctx context.Context, options Options) (context.Context, context.CancelFunc) {
+	if options.TimeoutMillis <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, time.Duration(options.TimeoutMillis)*time.Millisecond, errAnalysisTimeout)
+}
+
+// timedOut informa se ctx foi encerrado pelo timeout_ms, e não pelo chamador
+func timedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errAnalysisTimeout)
+}
+
+// timeoutWarning aviso de timeout_ms atingido
+func timeoutWarning(options Options) Warning {
+	return Warning{
+		Code:    warningLimitTimeout,
+		Message: fmt.Sprintf("analysis exceeded timeout_ms (%d)", options.TimeoutMillis),
+	}
+}
+
+// fileSizeWarning aviso de max_file_size atingido
+func fileSizeWarning(options Options) Warning {
+	return Warning{
+		Code:    warningLimitFileSize,
+		Message: fmt.Sprintf("document exceeds max_file_size (%d bytes)", options.MaxFileSize),
+	}
+}
+
+// anomaliesWarning aviso de max_anomalies atingido
+func anomaliesWarning(total int64, options Options) Warning {
+	return Warning{
+		Code:    warningLimitAnomalies,
+		Message: fmt.Sprintf("%d anomalies found, only the first %d recorded", total, options.MaxAnomalies),
+	}
+}
+
+// limitSkippedReport relatório de documento não analisado por limite
+func limitSkippedReport(path, reason string, warning Warning, options Options) *Report {
+	return &Report{
+		DocumentPath:        path,
+		Status:              StatusSkipped,
+		SkipReason:          reason,
+		EncodingAnomalies:   []EncodingAnomaly{},
+		SuggestedTransforms: []TextTransformation{},
+		EffectiveOptions:    options,
+		Warnings:            []Warning{warning},
+	}
+}
+
+// readLimited lê r inteiro, respeitando max_file_size. O segundo retorno é
+// true quando o documento excede o limite; nesse caso a leitura para ali.
+func readLimited(r io.Reader, options Options) ([]byte, bool, error) {
+	if options.MaxFileSize <= 0 {
+		data, err := io.ReadAll(r)
+		return data, false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, options.MaxFileSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, int64(len(data)) > options.MaxFileSize, nil
+}
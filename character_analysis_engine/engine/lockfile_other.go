@@ -0,0 +1,40 @@
+//go:build !unix
+
+package engine
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// Sem flock a trava é a existência do arquivo. Um arquivo mais antigo que
+// staleLockAge é de um processo que caiu e é removido.
+const (
+	staleLockAge    = 10 * time.Minute
+	lockFileTimeout = time.Minute
+)
+
+// lockFile trava exclusiva entre processos em path, esperando quem a tem.
+// Devolve a função que solta a trava.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockFileTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"io"
+	"os"
+)
+
+// MappedFile arquivo aberto para leitura aleatória. Em sistemas Unix o
+// conteúdo é mapeado em memória (mmap) e as leituras não copiam dados para o
+// heap; nos demais sistemas cai para leituras posicionais com ReadAt.
+type MappedFile struct {
+	file *os.File
+	size int64
+	// data mapeamento somente leitura; nil quando não há mmap
+	data []byte
+}
+
+// OpenMapped abre e mapeia o arquivo em path
+func OpenMapped(path string) (*MappedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to open document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, NewError(ErrCodeIO, "failed to stat document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+
+	mapped := &MappedFile{file: file, size: info.Size()}
+	if mapped.size > 0 {
+		data, err := mapFile(file, mapped.size)
+		if err != nil {
+			file.Close()
+			return nil, NewError(ErrCodeIO, "failed to map document", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+		mapped.data = data
+	}
+	return mapped, nil
+}
+
+// Size tamanho do arquivo em bytes
+func (m *MappedFile) Size() int64 {
+	return m.size
+}
+
+// ReadAt implementa io.ReaderAt
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if m.data == nil {
+		return m.file.ReadAt(p, off)
+	}
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= m.size {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Reader retorna um leitor sequencial sobre todo o arquivo
+func (m *MappedFile) Reader() io.Reader {
+	return io.NewSectionReader(m, 0, m.size)
+}
+
+// slice retorna os bytes [off, off+n). Com mmap é uma visão do mapeamento,
+// sem cópia, válida até Close; sem mmap é lida para um buffer novo.
+func (m *MappedFile) slice(off, n int64) ([]byte, error) {
+	if off < 0 || n < 0 || off+n > m.size {
+		return nil, NewError(ErrCodeInvalidArgument, "range outside of document", map[string]interface{}{"offset": off, "length": n, "size": m.size})
+	}
+	if m.data != nil {
+		return m.data[off : off+n : off+n], nil
+	}
+	buf := make([]byte, n)
+	if _, err := m.file.ReadAt(buf, off); err != nil && err != io.EOF {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"cause": err.Error()})
+	}
+	return buf, nil
+}
+
+// Close desfaz o mapeamento e fecha o arquivo
+func (m *MappedFile) Close() error {
+	var err error
+	if m.data != nil {
+		err = unmapFile(m.data)
+		m.data = nil
+	}
+	if closeErr := m.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package engine
+
+import "os"
+
+// mapFile sem mmap: MappedFile usa ReadAt
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	return nil, nil
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile mapeia o arquivo inteiro, somente leitura
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	// Em plataformas de 32 bits arquivos grandes não cabem no espaço de
+	// endereçamento: MappedFile usa ReadAt
+	if int64(int(size)) != size {
+		return nil, nil
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
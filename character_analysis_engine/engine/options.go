@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Options opções tipadas de análise. As chaves JSON seguem os nomes já
+// enviados pelo workbench (snake_case legado e camelCase da GUI).
+type Options struct {
+	// AggressiveMode habilita estratégias arriscadas: re-decodificação genérica
+	// de sequências Latin-1 (estratégia "pattern") e similaridade mais
+	// permissiva, e baixa o limiar de confiança em aggressiveThresholdShift
+	// para que essas correções passem com o limiar padrão.
+	AggressiveMode bool `json:"aggressive_mode"`
+
+	// BackupFiles preserva o original antes de qualquer escrita em disco.
+	BackupFiles bool `json:"backup_files"`
+
+	// ConfidenceThreshold pontuação mínima, entre 0 e 1, para que uma correção
+	// seja sugerida, 0.2 a menos no modo agressivo. Correções abaixo do
+	// limiar vão para rejectedTransforms.
+	ConfidenceThreshold float64 `json:"confidence_threshold"`
+
+	// FixMojibake habilita as correções da tabela de mojibake (estratégia "dictionary").
+	FixMojibake bool `json:"fixMojibake"`
+
+	// UseDictionary habilita as correções por similaridade com o dicionário.
+	UseDictionary bool `json:"useDictionary"`
+
+	// Parallel permite dividir documentos grandes em segmentos analisados em paralelo.
+	Parallel bool `json:"parallel"`
+
+	// MaxFileSize tamanho máximo, em bytes, de um documento lido por inteiro;
+	// documentos maiores saem com status skipped. A análise em fluxo não tem
+	// esse limite. 0 desativa.
+	MaxFileSize int64 `json:"max_file_size"`
+
+	// MaxAnomalies anomalias registradas por documento; as excedentes são
+	// apenas contadas em anomalyCount. 0 desativa.
+	MaxAnomalies int `json:"max_anomalies"`
+
+	// TimeoutMillis tempo máximo de análise de um documento. 0 desativa.
+	TimeoutMillis int64 `json:"timeout_ms"`
+
+	// explicit chaves definidas por ParseOptions, que prevalecem sobre a
+	// configuração de projeto (ver project.go)
+	explicit map[string]bool
+
+	// vocabulary palavras (em minúsculas) dos projetos do documento, aceitas
+	// além do dicionário da instância; vocabularyDigest entra na chave do cache
+	vocabulary       map[string]bool
+	vocabularyDigest string
+}
+
+// Warning aviso estruturado devolvido junto com o relatório
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Códigos de aviso
+const (
+	warningUnknownOption = "unknown_option"
+)
+
+// DefaultOptions retorna as opções usadas quando o chamador não informa nada
+func DefaultOptions() Options {
+	return Options{
+		AggressiveMode:      false,
+		BackupFiles:         true,
+		ConfidenceThreshold: 0.8,
+		FixMojibake:         true,
+		UseDictionary:       true,
+		Parallel:            true,
+		MaxFileSize:         512 << 20,
+		MaxAnomalies:        10000,
+		TimeoutMillis:       5 * 60 * 1000,
+	}
+}
+
+// knownAnalysisOptionKeys lista as chaves JSON reconhecidas em Options
+var knownAnalysisOptionKeys = map[string]bool{
+	"aggressive_mode":      true,
+	"backup_files":         true,
+	"confidence_threshold": true,
+	"fixMojibake":          true,
+	"useDictionary":        true,
+	"parallel":             true,
+	"max_file_size":        true,
+	"max_anomalies":        true,
+	"timeout_ms":           true,
+}
+
+// ParseOptions decodifica e valida opções em JSON, aplicando-as sobre base.
+// Uma string vazia resulta na própria base; chaves desconhecidas geram avisos
+// e valores de tipo ou faixa inválidos geram erro.
+func ParseOptions(jsonStr string, base Options) (Options, []Warning, error) {
+	options := base
+	if strings.TrimSpace(jsonStr) == "" {
+		return options, nil, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return options, nil, fmt.Errorf("malformed options: %w", err)
+	}
+
+	var warnings []Warning
+	unknown := make([]string, 0)
+	explicit := make(map[string]bool, len(base.explicit)+len(raw))
+	for key := range base.explicit {
+		explicit[key] = true
+	}
+	for key := range raw {
+		if !knownAnalysisOptionKeys[key] {
+			unknown = append(unknown, key)
+		} else {
+			explicit[key] = true
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		warnings = append(warnings, Warning{
+			Code:    warningUnknownOption,
+			Message: fmt.Sprintf("unknown option %q ignored", key),
+		})
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(jsonStr)))
+	if err := decoder.Decode(&options); err != nil {
+		return options, warnings, fmt.Errorf("invalid option value: %w", err)
+	}
+
+	options.explicit = explicit
+
+	if err := options.validate(); err != nil {
+		return options, warnings, err
+	}
+	return options, warnings, nil
+}
+
+// overlayExplicit copia para o os valores das chaves que options recebeu
+// de ParseOptions
+func (o Options) overlayExplicit(options Options) Options {
+	o.explicit = options.explicit
+	if len(options.explicit) == 0 {
+		return o
+	}
+	// Options só tem campos simples, então a ida e volta por JSON não falha
+	data, _ := json.Marshal(options)
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+	selected := make(map[string]json.RawMessage, len(options.explicit))
+	for key := range options.explicit {
+		selected[key] = all[key]
+	}
+	data, _ = json.Marshal(selected)
+	json.Unmarshal(data, &o)
+	o.explicit = options.explicit
+	return o
+}
+
+// explicitKeys chaves explícitas em ordem, para gravar junto com as opções
+func (o Options) explicitKeys() []string {
+	keys := make([]string, 0, len(o.explicit))
+	for key := range o.explicit {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withExplicit o com as chaves explícitas gravadas por explicitKeys
+func (o Options) withExplicit(keys []string) Options {
+	o.explicit = nil
+	if len(keys) > 0 {
+		o.explicit = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			o.explicit[key] = true
+		}
+	}
+	return o
+}
+
+// validate verifica as faixas de valores das opções
+func (o Options) validate() error {
+	if o.ConfidenceThreshold < 0 || o.ConfidenceThreshold > 1 {
+		return fmt.Errorf("confidence_threshold must be between 0 and 1, got %v", o.ConfidenceThreshold)
+	}
+	if o.MaxFileSize < 0 || o.MaxAnomalies < 0 || o.TimeoutMillis < 0 {
+		return fmt.Errorf("max_file_size, max_anomalies and timeout_ms must not be negative")
+	}
+	return nil
+}
+
+// aggressiveThresholdShift quanto o modo agressivo baixa o limiar: com o
+// padrão de 0.8, o limiar efetivo coincide com o corte de similaridade do
+// modo agressivo
+const aggressiveThresholdShift = 0.2
+
+// confidenceThreshold limiar efetivo de confiança
+func (o Options) confidenceThreshold() float64 {
+	if o.AggressiveMode {
+		return max(o.ConfidenceThreshold-aggressiveThresholdShift, 0)
+	}
+	return o.ConfidenceThreshold
+}
+
+// similarityCutoff similaridade mínima para a estratégia "similarity"
+func (o Options) similarityCutoff() float64 {
+	if o.AggressiveMode {
+		return 0.6
+	}
+	return 0.7
+}
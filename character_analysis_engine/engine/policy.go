@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Regras da política de caminhos, devolvidas em Details["rule"]
+const (
+	PathRuleEmpty               = "empty_path"
+	PathRuleNotFound            = "not_found"
+	PathRuleNotRegular          = "not_regular"
+	PathRuleNotDirectory        = "not_directory"
+	PathRuleOutsideRoots        = "outside_roots"
+	PathRuleExtensionDenied     = "extension_denied"
+	PathRuleExtensionNotAllowed = "extension_not_allowed"
+	PathRuleNotText             = "not_text"
+)
+
+// DefaultAllowedExtensions extensões aceitas quando a configuração não define outras
+var DefaultAllowedExtensions = []string{".txt", ".log", ".csv", ".json", ".xml", ".html", ".md"}
+
+// sniffSampleSize bytes lidos para decidir se um arquivo sem extensão é texto
+const sniffSampleSize = 8 << 10
+
+// PathPolicyConfig configuração da política de caminhos
+type PathPolicyConfig struct {
+	// AllowedRoots diretórios permitidos; vazio aceita qualquer diretório.
+	// Links simbólicos são resolvidos antes da verificação.
+	AllowedRoots []string `json:"allowedRoots"`
+	// AllowedExtensions nil usa DefaultAllowedExtensions; vazio aceita todas
+	AllowedExtensions []string `json:"allowedExtensions"`
+	// DeniedExtensions sempre rejeitadas, mesmo que estejam em AllowedExtensions
+	DeniedExtensions []string `json:"deniedExtensions"`
+	// SniffExtensionless aceita arquivos sem extensão cujo conteúdo parece
+	// texto (padrão true)
+	SniffExtensionless *bool `json:"sniffExtensionless"`
+}
+
+// PathPolicy política de caminhos de uma instância, imutável após a criação
+type PathPolicy struct {
+	roots              []string
+	allowedExtensions  map[string]bool
+	deniedExtensions   map[string]bool
+	sniffExtensionless bool
+}
+
+// NewPathPolicy valida a configuração e resolve os diretórios permitidos
+func NewPathPolicy(config PathPolicyConfig) (*PathPolicy, error) {
+	policy := &PathPolicy{
+		sniffExtensionless: config.SniffExtensionless == nil || *config.SniffExtensionless,
+		deniedExtensions:   extensionSet(config.DeniedExtensions),
+	}
+
+	allowed := config.AllowedExtensions
+	if allowed == nil {
+		allowed = DefaultAllowedExtensions
+	}
+	if len(allowed) > 0 {
+		policy.allowedExtensions = extensionSet(allowed)
+	}
+
+	for _, root := range config.AllowedRoots {
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return nil, NewError(ErrCodeInvalidArgument, "allowed root cannot be resolved", map[string]interface{}{"root": root, "cause": err.Error()})
+		}
+		info, err := os.Stat(resolved)
+		if err != nil || !info.IsDir() {
+			return nil, NewError(ErrCodeInvalidArgument, "allowed root is not a directory", map[string]interface{}{"root": root})
+		}
+		policy.roots = append(policy.roots, resolved)
+	}
+	return policy, nil
+}
+
+// extensionSet normaliza as extensões para minúsculas com ponto
+func extensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = true
+	}
+	return set
+}
+
+// resolvePath devolve o caminho absoluto, limpo e com os links resolvidos
+func resolvePath(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absolute)
+}
+
+// CheckFile verifica se o documento em path pode ser processado e devolve o
+// caminho resolvido
+func (p *PathPolicy) CheckFile(path string) (string, *Error) {
+	resolved, err := p.checkLocation(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, statErr := os.Stat(resolved)
+	if statErr != nil {
+		return "", pathRuleError(path, PathRuleNotFound, "file cannot be accessed", map[string]interface{}{"cause": statErr.Error()})
+	}
+	if !info.Mode().IsRegular() {
+		return "", pathRuleError(path, PathRuleNotRegular, "path is not a regular file", nil)
+	}
+
+	// A extensão considerada é a do arquivo real, não a do link
+	ext := strings.ToLower(filepath.Ext(resolved))
+	if p.deniedExtensions[ext] {
+		return "", pathRuleError(path, PathRuleExtensionDenied, "file type denied", map[string]interface{}{"extension": ext})
+	}
+	if ext == "" {
+		if p.deniedExtensions[""] {
+			return "", pathRuleError(path, PathRuleExtensionDenied, "files without extension are denied", nil)
+		}
+		if p.allowedExtensions == nil || p.allowedExtensions[""] {
+			return resolved, nil
+		}
+		if !p.sniffExtensionless {
+			return "", pathRuleError(path, PathRuleExtensionNotAllowed, "files without extension are not allowed", nil)
+		}
+		if !sniffText(resolved) {
+			return "", pathRuleError(path, PathRuleNotText, "file without extension does not look like text", nil)
+		}
+		return resolved, nil
+	}
+	if p.allowedExtensions != nil && !p.allowedExtensions[ext] {
+		return "", pathRuleError(path, PathRuleExtensionNotAllowed, "file type not allowed", map[string]interface{}{"extension": ext, "allowed": p.AllowedExtensions()})
+	}
+	return resolved, nil
+}
+
+// CheckDirectory verifica se o diretório está dentro das raízes permitidas
+func (p *PathPolicy) CheckDirectory(path string) (string, *Error) {
+	resolved, err := p.checkLocation(path)
+	if err != nil {
+		return "", err
+	}
+	info, statErr := os.Stat(resolved)
+	if statErr != nil || !info.IsDir() {
+		return "", pathRuleError(path, PathRuleNotDirectory, "path is not a directory", nil)
+	}
+	return resolved, nil
+}
+
+// checkLocation resolve o caminho e aplica a restrição de raízes
+func (p *PathPolicy) checkLocation(path string) (string, *Error) {
+	if strings.TrimSpace(path) == "" {
+		return "", pathRuleError(path, PathRuleEmpty, "path is empty", nil)
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", pathRuleError(path, PathRuleNotFound, "path does not exist", map[string]interface{}{"cause": err.Error()})
+	}
+	if p.allowsDirectory(resolved) {
+		return resolved, nil
+	}
+	return "", pathRuleError(path, PathRuleOutsideRoots, "path is outside the allowed roots", map[string]interface{}{"resolvedPath": resolved, "allowedRoots": p.roots})
+}
+
+// allowsDirectory informa se o caminho (já resolvido) está dentro das
+// raízes permitidas; sem raízes, qualquer caminho é aceito
+func (p *PathPolicy) allowsDirectory(dir string) bool {
+	if len(p.roots) == 0 {
+		return true
+	}
+	for _, root := range p.roots {
+		if isWithin(root, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedExtensions extensões aceitas; nil quando todas são aceitas
+func (p *PathPolicy) AllowedExtensions() []string {
+	if p.allowedExtensions == nil {
+		return nil
+	}
+	extensions := make([]string, 0, len(p.allowedExtensions))
+	for ext := range p.allowedExtensions {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// isWithin informa se path é root ou está abaixo dele (ambos já resolvidos)
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel))
+}
+
+// pathRuleError erro invalid_path com a regra violada
+func pathRuleError(path, rule, message string, details map[string]interface{}) *Error {
+	if details == nil {
+		details = make(map[string]interface{}, 2)
+	}
+	details["path"] = path
+	details["rule"] = rule
+	return NewError(ErrCodeInvalidPath, message, details)
+}
+
+// sniffText lê o início do arquivo e decide se parece texto (ver ClassifyContent)
+func sniffText(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	sample := make([]byte, sniffSampleSize)
+	n, _ := io.ReadFull(file, sample)
+	return ClassifyContent(sample[:n]).Class == ContentText
+}
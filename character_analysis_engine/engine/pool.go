@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ConcurrentProcessorPool struct {
+	processorCount       int
+	taskQueue            chan func(context.Context)
+	synchronizationGroup sync.WaitGroup
+	workerGroup          sync.WaitGroup
+	terminationSignal    chan struct{}
+	operationalStatus    atomic.Bool
+	lifecycleLock        sync.RWMutex
+	taskContext          context.Context
+	cancelTasks          context.CancelFunc
+}
+
+var errPoolStopped = errors.New("processor pool is not running")
+
+func NewConcurrentProcessorPool(processorCapacity int) *ConcurrentProcessorPool {
+	taskContext, cancelTasks := context.WithCancel(context.Background())
+	return &ConcurrentProcessorPool{
+		processorCount:    processorCapacity,
+		taskQueue:         make(chan func(context.Context), processorCapacity*2),
+		terminationSignal: make(chan struct{}),
+		taskContext:       taskContext,
+		cancelTasks:       cancelTasks,
+	}
+}
+
+func (w *ConcurrentProcessorPool) Start() {
+	w.lifecycleLock.Lock()
+	defer w.lifecycleLock.Unlock()
+	if w.operationalStatus.Load() {
+		return
+	}
+	w.operationalStatus.Store(true)
+	for i := 0; i < w.processorCount; i++ {
+		w.workerGroup.Add(1)
+		go w.worker()
+	}
+}
+
+func (w *ConcurrentProcessorPool) worker() {
+	defer w.workerGroup.Done()
+	for {
+		select {
+		case task := <-w.taskQueue:
+			// Tarefas ainda na fila após o cancelamento são descartadas
+			if w.taskContext.Err() == nil {
+				task(w.taskContext)
+			}
+			w.synchronizationGroup.Done()
+		case <-w.terminationSignal:
+			return
+		}
+	}
+}
+
+// Submit enfileira uma tarefa. O lock de leitura impede que Stop feche o pool
+// enquanto um envio está em andamento; a fila nunca é fechada.
+func (w *ConcurrentProcessorPool) Submit(fn func(context.Context)) error {
+	w.lifecycleLock.RLock()
+	defer w.lifecycleLock.RUnlock()
+	if !w.operationalStatus.Load() {
+		return errPoolStopped
+	}
+	w.synchronizationGroup.Add(1)
+	w.taskQueue <- fn
+	return nil
+}
+
+func (w *ConcurrentProcessorPool) Wait() {
+	w.synchronizationGroup.Wait()
+}
+
+// Stop recusa novas tarefas, aguarda a fila esvaziar por até timeout e então
+// cancela o que restar. Retorna false quando houve cancelamento.
+func (w *ConcurrentProcessorPool) Stop(timeout time.Duration) bool {
+	w.lifecycleLock.Lock()
+	if !w.operationalStatus.Load() {
+		// Parado ou nunca iniciado: só falta liberar o contexto das tarefas
+		w.cancelTasks()
+		w.lifecycleLock.Unlock()
+		return true
+	}
+	w.operationalStatus.Store(false)
+	w.lifecycleLock.Unlock()
+
+	drained := waitWithTimeout(&w.synchronizationGroup, timeout)
+	w.cancelTasks()
+	if !drained {
+		w.synchronizationGroup.Wait()
+	}
+	close(w.terminationSignal)
+	w.workerGroup.Wait()
+	return drained
+}
+
+// waitWithTimeout aguarda o WaitGroup por até timeout; retorna false se expirou
+func waitWithTimeout(group *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,390 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProjectConfigFile arquivo de configuração procurado do diretório de cada
+// documento para cima
+const ProjectConfigFile = ".demojibake.json"
+
+// projectConfigTOML só é detectado para avisar: o motor não tem dependências
+// externas e não lê TOML
+const projectConfigTOML = ".demojibake.toml"
+
+// skipReasonIgnored documento ignorado por ignore em um .demojibake.json
+const skipReasonIgnored = "ignored_by_config"
+
+// Códigos de aviso da configuração de projeto
+const (
+	warningProjectOption = "project_option"
+	warningProjectTOML   = "project_config_toml"
+)
+
+// ProjectConfig conteúdo de um .demojibake.json. Padrões de Ignore e de
+// Files seguem ScanOptions e são relativos ao diretório do arquivo. Com Root
+// a busca para aqui e os diretórios acima não são consultados.
+type ProjectConfig struct {
+	Root            bool              `json:"root"`
+	Options         json.RawMessage   `json:"options"`
+	Vocabulary      []string          `json:"vocabulary"`
+	VocabularyFiles []string          `json:"vocabularyFiles"`
+	Ignore          []string          `json:"ignore"`
+	Overrides       []ProjectOverride `json:"overrides"`
+}
+
+// ProjectOverride opções aplicadas aos documentos que casam com Files
+type ProjectOverride struct {
+	Files   []string        `json:"files"`
+	Options json.RawMessage `json:"options"`
+	Ignore  bool            `json:"ignore"`
+}
+
+// EffectiveConfig configuração que vale para um documento: padrões da
+// instância, depois cada .demojibake.json do mais externo ao mais interno,
+// cada um seguido das sobreposições que casam, e por fim as opções que o
+// chamador definiu explicitamente
+type EffectiveConfig struct {
+	Path       string    `json:"path"`
+	Sources    []string  `json:"sources"`
+	Overrides  []string  `json:"overrides"`
+	Ignored    bool      `json:"ignored"`
+	IgnoredBy  string    `json:"ignoredBy,omitempty"`
+	Vocabulary int       `json:"vocabulary"`
+	Options    Options   `json:"options"`
+	Warnings   []Warning `json:"warnings,omitempty"`
+
+	// Fingerprint identifica as opções, o ignore e o conteúdo dos arquivos
+	// de projeto que valem para o documento; o índice incremental o compara
+	Fingerprint string `json:"fingerprint"`
+}
+
+// loadedProject um .demojibake.json lido, com o vocabulário já carregado
+type loadedProject struct {
+	path       string
+	dir        string
+	modTime    time.Time
+	size       int64
+	config     ProjectConfig
+	vocabulary []string
+	warnings   []Warning
+	// vocabularyFiles arquivos de vocabulário lidos, para detectar mudanças
+	vocabularyFiles []fileStamp
+	// digest conteúdo do arquivo e do vocabulário carregado
+	digest string
+}
+
+// fileStamp tamanho e data de um arquivo quando foi lido
+type fileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// changed informa se o arquivo mudou ou sumiu desde a leitura
+func (s fileStamp) changed() bool {
+	info, err := os.Stat(s.path)
+	return err != nil || !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
+
+// projectConfigs arquivos já lidos, relidos quando mudam no disco. Arquivos
+// de configuração e de vocabulário passam por policy, como os documentos.
+type projectConfigs struct {
+	lock   sync.Mutex
+	files  map[string]*loadedProject
+	policy *PathPolicy
+}
+
+// load devolve o arquivo em path, ou nil se não existe
+func (p *projectConfigs) load(path string) (*loadedProject, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to stat project config", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	// Um link pode apontar para fora das raízes permitidas
+	if _, pathErr := p.policy.checkLocation(path); pathErr != nil {
+		return nil, pathErr
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.files == nil {
+		p.files = make(map[string]*loadedProject)
+	}
+	if cached, ok := p.files[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() && !cached.vocabularyChanged() {
+		return cached, nil
+	}
+	project, err := readProjectConfig(path, info, p.policy)
+	if err != nil {
+		return nil, err
+	}
+	p.files[path] = project
+	return project, nil
+}
+
+// vocabularyChanged informa se algum arquivo de vocabulário mudou
+func (l *loadedProject) vocabularyChanged() bool {
+	for _, stamp := range l.vocabularyFiles {
+		if stamp.changed() {
+			return true
+		}
+	}
+	return false
+}
+
+// readProjectConfig lê e valida um .demojibake.json. Como na configuração
+// da instância, chaves desconhecidas no nível do arquivo são erro; dentro
+// de options viram avisos, como nas opções de cada chamada. Arquivos de
+// vocabulário fora das raízes de policy são recusados.
+func readProjectConfig(path string, info os.FileInfo, policy *PathPolicy) (*loadedProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read project config", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	project := &loadedProject{path: path, dir: filepath.Dir(path), modTime: info.ModTime(), size: info.Size()}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&project.config); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, "invalid project config", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+
+	check := func(raw json.RawMessage, where string) error {
+		_, warnings, err := ParseOptions(string(raw), DefaultOptions())
+		if err != nil {
+			return NewError(ErrCodeInvalidOptions, err.Error(), map[string]interface{}{"path": path, "section": where})
+		}
+		for _, warning := range warnings {
+			warning.Code = warningProjectOption
+			warning.Message = fmt.Sprintf("%s (%s, %s)", warning.Message, path, where)
+			project.warnings = append(project.warnings, warning)
+		}
+		return nil
+	}
+	if err := check(project.config.Options, "options"); err != nil {
+		return nil, err
+	}
+	for i, override := range project.config.Overrides {
+		if len(override.Files) == 0 {
+			return nil, NewError(ErrCodeInvalidOptions, "override without files", map[string]interface{}{"path": path, "override": i})
+		}
+		if err := check(override.Options, fmt.Sprintf("overrides[%d]", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	project.vocabulary = append(project.vocabulary, project.config.Vocabulary...)
+	for _, name := range project.config.VocabularyFiles {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(project.dir, name)
+		}
+		resolved, pathErr := policy.checkLocation(name)
+		if pathErr != nil {
+			pathErr.Details["project"] = path
+			return nil, pathErr
+		}
+		vocabularyInfo, err := os.Stat(resolved)
+		if err != nil {
+			return nil, NewError(ErrCodeIO, "failed to read project vocabulary", map[string]interface{}{"path": path, "vocabulary": name, "cause": err.Error()})
+		}
+		words, err := os.ReadFile(resolved)
+		if err != nil {
+			return nil, NewError(ErrCodeIO, "failed to read project vocabulary", map[string]interface{}{"path": path, "vocabulary": name, "cause": err.Error()})
+		}
+		project.vocabularyFiles = append(project.vocabularyFiles, fileStamp{path: resolved, modTime: vocabularyInfo.ModTime(), size: vocabularyInfo.Size()})
+		for _, line := range strings.Split(string(words), "\n") {
+			if word := strings.TrimSpace(line); word != "" {
+				project.vocabulary = append(project.vocabulary, word)
+			}
+		}
+	}
+
+	digest := sha256.New()
+	digest.Write(data)
+	for _, word := range project.vocabulary {
+		digest.Write([]byte{0})
+		digest.Write([]byte(word))
+	}
+	project.digest = hex.EncodeToString(digest.Sum(nil))
+	return project, nil
+}
+
+// discover arquivos de configuração que valem para path, do mais externo
+// ao mais interno. A busca não sai das raízes permitidas por policy.
+func (p *projectConfigs) discover(path string) ([]*loadedProject, []Warning, error) {
+	var found []*loadedProject
+	var warnings []Warning
+	dir := filepath.Dir(path)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	for p.policy.allowsDirectory(dir) {
+		project, err := p.load(filepath.Join(dir, ProjectConfigFile))
+		if err != nil {
+			return nil, nil, err
+		}
+		if project != nil {
+			found = append(found, project)
+		} else if _, err := os.Stat(filepath.Join(dir, projectConfigTOML)); err == nil {
+			warnings = append(warnings, Warning{
+				Code:    warningProjectTOML,
+				Message: fmt.Sprintf("%s ignored: only %s is supported", filepath.Join(dir, projectConfigTOML), ProjectConfigFile),
+			})
+		}
+		if project != nil && project.config.Root {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found, warnings, nil
+}
+
+// EffectiveConfig resolve a configuração de projeto para path, sobre os
+// padrões da instância. As opções que options recebeu explicitamente (por
+// ParseOptions) têm a palavra final. O vocabulário dos projetos encontrados
+// vale só para path: vai nas opções devolvidas, não no dicionário da instância.
+func (e *Engine) EffectiveConfig(path string, options Options) (*EffectiveConfig, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	return e.effectiveConfig(path, options)
+}
+
+// effectiveConfig EffectiveConfig para quem já está dentro de acquire
+func (e *Engine) effectiveConfig(path string, options Options) (*EffectiveConfig, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, NewError(ErrCodeInvalidPath, "failed to resolve path", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	projects, warnings, err := e.projects.discover(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EffectiveConfig{Path: path, Sources: []string{}, Overrides: []string{}, Warnings: warnings}
+	merged := e.defaultOptions
+	apply := func(raw json.RawMessage) {
+		// Os arquivos já foram validados ao serem lidos
+		if parsed, _, err := ParseOptions(string(raw), merged); err == nil {
+			merged = parsed
+		}
+	}
+	vocabulary := map[string]bool{}
+	vocabularyDigest := sha256.New()
+	for _, project := range projects {
+		result.Sources = append(result.Sources, project.path)
+		result.Warnings = append(result.Warnings, project.warnings...)
+		result.Vocabulary += len(project.vocabulary)
+		for _, word := range project.vocabulary {
+			vocabulary[strings.ToLower(word)] = true
+		}
+		vocabularyDigest.Write([]byte(project.digest))
+		apply(project.config.Options)
+
+		rel, err := filepath.Rel(project.dir, absPath)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(project.config.Ignore, rel) {
+			result.Ignored, result.IgnoredBy = true, project.path
+		}
+		for i, override := range project.config.Overrides {
+			if !matchAny(override.Files, rel) {
+				continue
+			}
+			result.Overrides = append(result.Overrides, fmt.Sprintf("%s#%d %s", project.path, i, strings.Join(override.Files, ",")))
+			apply(override.Options)
+			if override.Ignore {
+				result.Ignored, result.IgnoredBy = true, fmt.Sprintf("%s#%d", project.path, i)
+			}
+		}
+	}
+	result.Options = merged.overlayExplicit(options)
+	if len(vocabulary) > 0 {
+		result.Options.vocabulary = vocabulary
+		result.Options.vocabularyDigest = hex.EncodeToString(vocabularyDigest.Sum(nil))
+	}
+	result.Fingerprint = projectFingerprint(result, projects)
+	return result, nil
+}
+
+// projectFingerprint resume o que a configuração de projeto decidiu para um
+// documento
+func projectFingerprint(effective *EffectiveConfig, projects []*loadedProject) string {
+	encodedOptions, _ := json.Marshal(effective.Options)
+	hash := sha256.New()
+	hash.Write(encodedOptions)
+	hash.Write([]byte{0})
+	hash.Write([]byte(strconv.FormatBool(effective.Ignored)))
+	for _, project := range projects {
+		hash.Write([]byte{0})
+		hash.Write([]byte(project.path))
+		hash.Write([]byte{0})
+		hash.Write([]byte(project.digest))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// projectFingerprintFor Fingerprint da configuração de projeto de path, ou
+// vazio sem projectConfig ou se ela não pode ser lida
+func (e *Engine) projectFingerprintFor(path string, options Options) string {
+	_, effective, err := e.OptionsForPath(path, options)
+	if err != nil || effective == nil {
+		return ""
+	}
+	return effective.Fingerprint
+}
+
+// OptionsForPath opções para analisar path. Sem projectConfig na
+// configuração da instância devolve options como veio; com, aplica a
+// configuração de projeto e informa se o documento deve ser ignorado.
+func (e *Engine) OptionsForPath(path string, options Options) (Options, *EffectiveConfig, error) {
+	if !e.config.ProjectConfig {
+		return options, nil, nil
+	}
+	effective, err := e.effectiveConfig(path, options)
+	if err != nil {
+		return options, nil, err
+	}
+	return effective.Options, effective, nil
+}
+
+// IgnoredReport relatório skipped de um documento que a configuração de
+// projeto manda ignorar
+func (c *EffectiveConfig) IgnoredReport() *Report {
+	return limitSkippedReport(c.Path, skipReasonIgnored, Warning{
+		Code:    skipReasonIgnored,
+		Message: "ignored by " + c.IgnoredBy,
+	}, c.Options)
+}
+
+// withProjectWarnings acrescenta a report os avisos da configuração de projeto
+func withProjectWarnings(report *Report, effective *EffectiveConfig) *Report {
+	if report != nil && effective != nil && len(effective.Warnings) > 0 {
+		report.Warnings = append(report.Warnings, effective.Warnings...)
+	}
+	return report
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"time"
+)
+
+// Report estrutura para resultados
+type Report struct {
+	DocumentPath          string                   `json:"documentPath"`
+	Status                string                   `json:"status"`
+	SkipReason            string                   `json:"skipReason,omitempty"`
+	ContentClass          *ContentClassification   `json:"contentClass,omitempty"`
+	ContentSHA256         string                   `json:"contentSha256,omitempty"`
+	SourceCharacterSet    string                   `json:"sourceCharacterSet"`
+	InferredCharacterSet  string                   `json:"inferredCharacterSet"`
+	AccuracyScore         float64                  `json:"accuracyScore"`
+	EncodingAnomalies     []EncodingAnomaly        `json:"encodingAnomalies"`
+	AnomalyCount          int                      `json:"anomalyCount"`
+	AnomaliesTruncated    bool                     `json:"anomaliesTruncated,omitempty"`
+	AnomalyTotals         []AnomalyTotal           `json:"anomalyTotals,omitempty"`
+	SuggestedTransforms   []TextTransformation     `json:"suggestedTransforms"`
+	RejectedTransforms    []RejectedTransformation `json:"rejectedTransforms,omitempty"`
+	EffectiveOptions      Options                  `json:"effectiveOptions"`
+	Warnings              []Warning                `json:"warnings,omitempty"`
+	AnalysisDuration      time.Duration            `json:"analysisDuration"`
+	TransformationSuccess bool                     `json:"transformationSuccess"`
+	FromCache             bool                     `json:"fromCache,omitempty"`
+}
+
+type EncodingAnomaly struct {
+	AnomalyCategory string `json:"anomalyCategory"`
+	TextPosition    int    `json:"textPosition"`
+	AffectedLength  int    `json:"affectedLength"`
+	SurroundingText string `json:"surroundingText"`
+	SeverityLevel   string `json:"severityLevel"`
+}
+
+// AnomalyTotal contagem por categoria e severidade, incluindo as anomalias
+// que não foram registradas por causa de max_anomalies
+type AnomalyTotal struct {
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+}
+
+// TextTransformation uma edição sugerida. ID é derivado da posição e dos
+// dois trechos (ver transformationID): a mesma análise do mesmo conteúdo
+// produz sempre os mesmos IDs.
+type TextTransformation struct {
+	ID                         string  `json:"id,omitempty"`
+	DocumentPosition           int     `json:"documentPosition"`
+	OriginalSequence           string  `json:"originalSequence"`
+	TransformedSequence        string  `json:"transformedSequence"`
+	TransformationScore        float64 `json:"transformationScore"`
+	TextTransformationStrategy string  `json:"correctionStrategy"`
+}
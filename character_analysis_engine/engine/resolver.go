@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"sort"
+)
+
+// Prioridade das estratégias de correção em caso de empate de pontuação.
+// Correções de dicionário são determinísticas e por isso vencem as demais.
+var transformationStrategyPriority = map[string]int{
+	"dictionary": 3,
+	"pattern":    2,
+	"similarity": 1,
+}
+
+// Motivos de rejeição registrados pelo resolvedor
+const (
+	rejectionOverlap        = "overlap"
+	rejectionDuplicate      = "duplicate"
+	rejectionInvalidRange   = "invalid_range"
+	rejectionBelowThreshold = "below_threshold"
+)
+
+// RejectedTransformation registra uma correção candidata descartada pelo resolvedor
+type RejectedTransformation struct {
+	Transformation  TextTransformation  `json:"transformation"`
+	RejectionReason string              `json:"rejectionReason"`
+	ConflictsWith   *TextTransformation `json:"conflictsWith,omitempty"`
+}
+
+// transformationEnd retorna o offset (em bytes) logo após o trecho afetado
+func transformationEnd(t TextTransformation) int {
+	return t.DocumentPosition + len(t.OriginalSequence)
+}
+
+// resolveTextTransformations converte as correções candidatas em uma lista de
+// edições sem sobreposição, ordenada por posição. Em caso de conflito vence a
+// maior pontuação, depois a estratégia de maior prioridade, depois o trecho
+// mais longo e por fim a menor posição. As candidatas descartadas são
+// retornadas com o motivo da rejeição.
+func resolveTextTransformations(content string, candidates []TextTransformation) ([]TextTransformation, []RejectedTransformation) {
+	var rejected []RejectedTransformation
+
+	valid := make([]TextTransformation, 0, len(candidates))
+	for _, candidate := range candidates {
+		end := transformationEnd(candidate)
+		if candidate.DocumentPosition < 0 || len(candidate.OriginalSequence) == 0 || end > len(content) ||
+			content[candidate.DocumentPosition:end] != candidate.OriginalSequence {
+			rejected = append(rejected, RejectedTransformation{
+				Transformation:  candidate,
+				RejectionReason: rejectionInvalidRange,
+			})
+			continue
+		}
+		valid = append(valid, candidate)
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		a, b := valid[i], valid[j]
+		if a.TransformationScore != b.TransformationScore {
+			return a.TransformationScore > b.TransformationScore
+		}
+		pa := transformationStrategyPriority[a.TextTransformationStrategy]
+		pb := transformationStrategyPriority[b.TextTransformationStrategy]
+		if pa != pb {
+			return pa > pb
+		}
+		if len(a.OriginalSequence) != len(b.OriginalSequence) {
+			return len(a.OriginalSequence) > len(b.OriginalSequence)
+		}
+		return a.DocumentPosition < b.DocumentPosition
+	})
+
+	// accepted é mantida ordenada por posição para a busca binária de conflitos
+	var accepted []TextTransformation
+	for _, candidate := range valid {
+		i := sort.Search(len(accepted), func(k int) bool {
+			return transformationEnd(accepted[k]) > candidate.DocumentPosition
+		})
+		if i == len(accepted) || accepted[i].DocumentPosition >= transformationEnd(candidate) {
+			accepted = append(accepted, TextTransformation{})
+			copy(accepted[i+1:], accepted[i:])
+			accepted[i] = candidate
+			continue
+		}
+
+		winner := accepted[i]
+		reason := rejectionOverlap
+		if winner.DocumentPosition == candidate.DocumentPosition &&
+			winner.OriginalSequence == candidate.OriginalSequence &&
+			winner.TransformedSequence == candidate.TransformedSequence {
+			reason = rejectionDuplicate
+		}
+		rejected = append(rejected, RejectedTransformation{
+			Transformation:  candidate,
+			RejectionReason: reason,
+			ConflictsWith:   &winner,
+		})
+	}
+
+	sort.SliceStable(rejected, func(i, j int) bool {
+		return rejected[i].Transformation.DocumentPosition < rejected[j].Transformation.DocumentPosition
+	})
+
+	return accepted, rejected
+}
@@ -0,0 +1,354 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Motivos pelos quais a varredura ignora um arquivo ou diretório
+const (
+	SkipExcluded    = "excluded"
+	SkipNotIncluded = "not_included"
+	SkipGitignored  = "gitignored"
+	SkipMaxDepth    = "max_depth"
+	SkipTooLarge    = "too_large"
+	SkipSymlink     = "symlink"
+	SkipNotRegular  = "not_regular"
+	SkipUnreadable  = "unreadable"
+	SkipScanIndex   = "scan_index"
+)
+
+// ScanOptions filtros da varredura de diretórios. Padrões sem "/" comparam
+// com o nome do arquivo; padrões com "/" comparam com o caminho relativo à
+// raiz. "**" casa com qualquer número de diretórios.
+type ScanOptions struct {
+	// Include se não vazio, só arquivos que casam com algum padrão
+	Include []string `json:"include"`
+	// Exclude arquivos e diretórios que casam são ignorados
+	Exclude []string `json:"exclude"`
+	// MaxDepth 0 sem limite; 1 apenas os arquivos da raiz
+	MaxDepth int `json:"maxDepth"`
+	// MaxFileSize 0 sem limite
+	MaxFileSize int64 `json:"maxFileSize"`
+	// Gitignore respeita os .gitignore encontrados e ignora o diretório .git
+	Gitignore bool `json:"gitignore"`
+
+	// Accept filtro adicional do host; retorna "" para aceitar o arquivo ou
+	// o motivo da rejeição
+	Accept func(path string) string `json:"-"`
+}
+
+// SkippedFile arquivo ou diretório ignorado pela varredura
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ScanResult arquivos encontrados e ignorados, na ordem da varredura
+type ScanResult struct {
+	Root    string        `json:"root"`
+	Files   []string      `json:"files"`
+	Skipped []SkippedFile `json:"skipped"`
+}
+
+// Scan percorre root recursivamente e devolve os arquivos que passam pelos filtros
+func Scan(ctx context.Context, root string, options ScanOptions) (*ScanResult, error) {
+	result := &ScanResult{Root: root, Files: []string{}, Skipped: []SkippedFile{}}
+	err := walkDirectory(ctx, root, options, func(path string) error {
+		result.Files = append(result.Files, path)
+		return nil
+	}, func(skipped SkippedFile) {
+		result.Skipped = append(result.Skipped, skipped)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AnalyzeDirectory varre root e entrega cada arquivo ao pool de workers assim
+// que ele é encontrado, sem esperar o fim da varredura. visit é chamado de
+// forma concorrente, como em AnalyzeFiles.
+func (e *Engine) AnalyzeDirectory(ctx context.Context, root string, scan ScanOptions, options Options, visit func(path string, report *Report, err error)) (*ScanResult, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+
+	e.totalFiles.Store(0)
+	e.processing.Store(0)
+
+	var batch sync.WaitGroup
+	result := &ScanResult{Root: root, Files: []string{}, Skipped: []SkippedFile{}}
+	err := walkDirectory(ctx, root, scan, func(path string) error {
+		result.Files = append(result.Files, path)
+		e.totalFiles.Add(1)
+		return e.submitAnalysis(ctx, &batch, path, options, visit)
+	}, func(skipped SkippedFile) {
+		result.Skipped = append(result.Skipped, skipped)
+	})
+	batch.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ignoreRule regra de um .gitignore
+type ignoreRule struct {
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// scanWalker estado da varredura
+type scanWalker struct {
+	ctx     context.Context
+	root    string
+	options ScanOptions
+	onFile  func(path string) error
+	onSkip  func(SkippedFile)
+}
+
+// walkDirectory valida as opções e percorre root em ordem lexicográfica
+func walkDirectory(ctx context.Context, root string, options ScanOptions, onFile func(path string) error, onSkip func(SkippedFile)) error {
+	if options.MaxDepth < 0 || options.MaxFileSize < 0 {
+		return NewError(ErrCodeInvalidArgument, "maxDepth and maxFileSize must not be negative", map[string]interface{}{"maxDepth": options.MaxDepth, "maxFileSize": options.MaxFileSize})
+	}
+	for _, pattern := range append(append([]string{}, options.Include...), options.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return NewError(ErrCodeInvalidArgument, "invalid glob pattern", map[string]interface{}{"pattern": pattern})
+		}
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return NewError(ErrCodeInvalidPath, "directory does not exist", map[string]interface{}{"path": root, "cause": err.Error()})
+	}
+	if !info.IsDir() {
+		return NewError(ErrCodeInvalidPath, "path is not a directory", map[string]interface{}{"path": root})
+	}
+
+	walker := &scanWalker{ctx: ctx, root: root, options: options, onFile: onFile, onSkip: onSkip}
+	return walker.walk(root, "", 1, nil)
+}
+
+// walk visita dir, cujo caminho relativo à raiz é rel (com "/")
+func (w *scanWalker) walk(dir, rel string, depth int, rules []ignoreRule) error {
+	if err := w.ctx.Err(); err != nil {
+		return errCancelled(dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.onSkip(SkippedFile{Path: dir, Reason: SkipUnreadable, Detail: err.Error()})
+		return nil
+	}
+	if w.options.Gitignore {
+		rules = append(rules[:len(rules):len(rules)], readGitignore(filepath.Join(dir, ".gitignore"), rel)...)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		entryPath := filepath.Join(dir, name)
+		entryRel := name
+		if rel != "" {
+			entryRel = rel + "/" + name
+		}
+		isDir := entry.IsDir()
+
+		switch {
+		case entry.Type()&os.ModeSymlink != 0:
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipSymlink})
+			continue
+		case w.options.Gitignore && isDir && name == ".git":
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipGitignored})
+			continue
+		case w.options.Gitignore && isIgnored(rules, entryRel, isDir):
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipGitignored})
+			continue
+		case matchAny(w.options.Exclude, entryRel):
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipExcluded})
+			continue
+		}
+
+		if isDir {
+			if w.options.MaxDepth > 0 && depth >= w.options.MaxDepth {
+				w.onSkip(SkippedFile{Path: entryPath, Reason: SkipMaxDepth})
+				continue
+			}
+			if err := w.walk(entryPath, entryRel, depth+1, rules); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !entry.Type().IsRegular() {
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipNotRegular})
+			continue
+		}
+		if len(w.options.Include) > 0 && !matchAny(w.options.Include, entryRel) {
+			w.onSkip(SkippedFile{Path: entryPath, Reason: SkipNotIncluded})
+			continue
+		}
+		if w.options.MaxFileSize > 0 {
+			info, err := entry.Info()
+			if err != nil {
+				w.onSkip(SkippedFile{Path: entryPath, Reason: SkipUnreadable, Detail: err.Error()})
+				continue
+			}
+			if info.Size() > w.options.MaxFileSize {
+				w.onSkip(SkippedFile{Path: entryPath, Reason: SkipTooLarge, Detail: formatSize(info.Size())})
+				continue
+			}
+		}
+		if w.options.Accept != nil {
+			if reason := w.options.Accept(entryPath); reason != "" {
+				w.onSkip(SkippedFile{Path: entryPath, Reason: reason})
+				continue
+			}
+		}
+		if err := w.onFile(entryPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchAny informa se o caminho relativo casa com algum padrão
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob compara com o nome quando o padrão não tem "/", senão com o
+// caminho relativo inteiro
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments casa segmento a segmento; "**" consome zero ou mais segmentos
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// readGitignore lê as regras do arquivo; base é o diretório dele relativo à raiz
+func readGitignore(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// Uma "/" no início ou no meio ancora o padrão no diretório do .gitignore
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored aplica as regras em ordem; a última que casa decide
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchSegments(rule.segments, strings.Split(sub, "/"))
+		} else {
+			matched, _ = path.Match(rule.segments[0], path.Base(sub))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// formatSize tamanho legível para os detalhes de arquivos ignorados
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(size)/float64(div), 'f', 1, 64) + " " + string("KMGTPE"[exp]) + "iB"
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// DefaultSegmentSize tamanho alvo dos segmentos quando um documento grande é
+// analisado em paralelo. Documentos menores que dois segmentos não são divididos.
+const DefaultSegmentSize = 1 << 20
+
+// textSegment trecho [start, end) do conteúdo, terminando em quebra de linha
+type textSegment struct {
+	start int
+	end   int
+}
+
+// segmentResult resultado de um segmento, com posições já absolutas
+type segmentResult struct {
+	issues      []EncodingAnomaly
+	tally       anomalyTally
+	corrections []TextTransformation
+	rejected    []RejectedTransformation
+}
+
+// splitSegments divide o conteúdo em segmentos de pelo menos size bytes,
+// sempre logo após uma quebra de linha. Nenhuma sequência de mojibake nem
+// palavra atravessa uma quebra de linha, então cada segmento pode ser
+// analisado sozinho; só o contexto em volta vem dos vizinhos (ver analyzeSegment).
+func splitSegments(content string, size int) []textSegment {
+	if len(content) < 2*size {
+		return []textSegment{{0, len(content)}}
+	}
+
+	var segments []textSegment
+	start := 0
+	for start < len(content) {
+		end := len(content)
+		if start+size < len(content) {
+			if i := strings.IndexByte(content[start+size:], '\n'); i >= 0 {
+				end = start + size + i + 1
+			}
+		}
+		segments = append(segments, textSegment{start, end})
+		start = end
+	}
+	return segments
+}
+
+// analyzeSegments analisa os segmentos concorrentemente e devolve os
+// resultados na ordem dos segmentos, o que torna a junção determinística.
+// Deve ser chamada com dictionaryLock em leitura: todos os segmentos usam o
+// mesmo snapshot do dicionário, sem travar de novo.
+func (e *Engine) analyzeSegments(ctx context.Context, content string, segments []textSegment, options Options) ([]segmentResult, error) {
+	results := make([]segmentResult, len(segments))
+	if len(segments) == 1 {
+		results[0] = e.analyzeSegment(ctx, content, segments[0], options)
+		return results, ctx.Err()
+	}
+
+	var wg sync.WaitGroup
+	for i, segment := range segments {
+		wg.Add(1)
+		go func(i int, segment textSegment) {
+			defer wg.Done()
+			// Os slots limitam o total de segmentos em análise na instância,
+			// inclusive quando vários documentos do lote são grandes
+			select {
+			case e.segmentSlots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-e.segmentSlots }()
+			if ctx.Err() != nil {
+				return
+			}
+			results[i] = e.analyzeSegment(ctx, content, segment, options)
+		}(i, segment)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// analyzeSegment detecta e resolve as correções de um segmento. O contexto
+// de anomalias e correções vem do documento inteiro, então o resultado é o
+// mesmo de uma análise sem divisão. Se ctx for cancelado no meio o resultado
+// é parcial; analyzeSegments descarta tudo.
+func (e *Engine) analyzeSegment(ctx context.Context, content string, segment textSegment, options Options) segmentResult {
+	text := content[segment.start:segment.end]
+	check := newCancelCheck(ctx)
+
+	issues, tally := detectEncodingEncodingAnomalys(check, text, options.MaxAnomalies)
+	for i := range issues {
+		issues[i].TextPosition += segment.start
+		issues[i].SurroundingText = extractContext(content, issues[i].TextPosition, anomalyContextRadius[issues[i].AnomalyCategory])
+	}
+
+	// As candidatas saem de uma janela que avança ngramContextRadius bytes
+	// sobre os vizinhos, para que os n-gramas vejam o texto do outro lado da
+	// borda; ficam só as que começam no segmento
+	windowStart := max(segment.start-ngramContextRadius, 0)
+	windowEnd := min(segment.end+ngramContextRadius, len(content))
+	var candidates []TextTransformation
+	for _, candidate := range e.applyIntelligentTextTransformations(check, content[windowStart:windowEnd], options) {
+		candidate.DocumentPosition += windowStart
+		if candidate.DocumentPosition >= segment.start && candidate.DocumentPosition < segment.end {
+			candidates = append(candidates, candidate)
+		}
+	}
+	candidates, belowThreshold := filterByConfidence(candidates, options.confidenceThreshold())
+	corrections, rejected := resolveTextTransformations(content, candidates)
+	rejected = append(belowThreshold, rejected...)
+	return segmentResult{issues: issues, tally: tally, corrections: corrections, rejected: rejected}
+}
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Motivos pelos quais uma correção aceita não foi aplicada
+const (
+	selectUnknown  = "unknown_id"
+	selectMismatch = "text_mismatch"
+	selectOverlap  = "overlaps_accepted"
+)
+
+// transformationID identificador estável de uma edição: os 16 primeiros
+// dígitos do SHA-256 da posição e dos dois trechos
+func transformationID(t TextTransformation) string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(t.DocumentPosition)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(t.OriginalSequence))
+	hash.Write([]byte{0})
+	hash.Write([]byte(t.TransformedSequence))
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// UnappliedTransformation correção aceita que ficou de fora, e por quê
+type UnappliedTransformation struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// SelectiveApply resultado de ApplySelected. Written é falso quando nenhuma
+// das correções aceitas pôde ser aplicada e o arquivo ficou intocado.
+type SelectiveApply struct {
+	Path       string                    `json:"path"`
+	Applied    []TextTransformation      `json:"applied"`
+	NotApplied []UnappliedTransformation `json:"notApplied"`
+	Rejected   int                       `json:"rejected"`
+	Written    bool                      `json:"written"`
+	PreSHA256  string                    `json:"preSha256"`
+	PostSHA256 string                    `json:"postSha256,omitempty"`
+}
+
+// ApplySelected grava em path apenas as correções de report cujos IDs estão
+// em acceptedIDs (repetidos contam uma vez); as demais sugestões contam
+// como rejeitadas. Entre correções aceitas que se sobrepõem vale a que veio
+// antes em acceptedIDs; as outras ficam em NotApplied. O arquivo precisa ter o mesmo SHA-256 de quando report
+// foi gerado. A gravação segue WriteFixed, com as opções efetivas da análise.
+func (e *Engine) ApplySelected(ctx context.Context, path string, report *Report, acceptedIDs []string) (*SelectiveApply, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	data, err := readAnalyzedDocument(path, report)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errCancelled(path, err)
+	}
+
+	suggested := suggestedByID(report)
+	result := &SelectiveApply{Path: path, Applied: []TextTransformation{}, NotApplied: []UnappliedTransformation{}, PreSHA256: report.ContentSHA256}
+
+	// O hash confere, então o texto decodificado é o mesmo da análise; ainda
+	// assim cada edição é conferida antes de entrar na lista
+	content, _ := decodeDocument(data)
+	seen := make(map[string]bool, len(acceptedIDs))
+	accepted := 0
+	// taken trechos já aceitos, em ordem de posição, para achar sobreposições
+	var taken []TextTransformation
+	for _, id := range acceptedIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		t, ok := suggested[id]
+		if !ok {
+			result.NotApplied = append(result.NotApplied, UnappliedTransformation{ID: id, Reason: selectUnknown})
+			continue
+		}
+		accepted++
+		if !matchesContent(content, t) {
+			result.NotApplied = append(result.NotApplied, UnappliedTransformation{ID: id, Reason: selectMismatch})
+			continue
+		}
+		i := sort.Search(len(taken), func(k int) bool {
+			return transformationEnd(taken[k]) > t.DocumentPosition
+		})
+		if i < len(taken) && taken[i].DocumentPosition < transformationEnd(t) {
+			result.NotApplied = append(result.NotApplied, UnappliedTransformation{ID: id, Reason: selectOverlap})
+			continue
+		}
+		taken = append(taken, TextTransformation{})
+		copy(taken[i+1:], taken[i:])
+		taken[i] = t
+		result.Applied = append(result.Applied, t)
+	}
+	result.Rejected = len(suggested) - accepted
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	fixed, err := ApplyTransformations(content, result.Applied)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.applyFixed(AuditSelect, path, report.ContentSHA256, fixed, result.Applied, report.EffectiveOptions); err != nil {
+		return nil, err
+	}
+	result.Written = true
+	result.PostSHA256 = hashContent([]byte(fixed))
+	return result, nil
+}
+
+// readAnalyzedDocument lê path e confere que é o mesmo conteúdo de report
+func readAnalyzedDocument(path string, report *Report) ([]byte, error) {
+	if report.ContentSHA256 == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "analysis has no content hash", map[string]interface{}{"path": path})
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	if current := hashContent(data); current != report.ContentSHA256 {
+		return nil, NewError(ErrCodeStaleAnalysis, "document changed since it was analyzed", map[string]interface{}{
+			"path":     path,
+			"expected": report.ContentSHA256,
+			"actual":   current,
+		})
+	}
+	return data, nil
+}
+
+// matchesContent informa se t ainda descreve content: a posição cabe no
+// texto e o trecho nela é o original da correção. Relatórios vêm de fora
+// (JSON do host ou da CLI), então nada garante isso.
+func matchesContent(content string, t TextTransformation) bool {
+	end := transformationEnd(t)
+	return t.DocumentPosition >= 0 && end <= len(content) && content[t.DocumentPosition:end] == t.OriginalSequence
+}
+
+// suggestedByID correções sugeridas de report indexadas pelo ID
+func suggestedByID(report *Report) map[string]TextTransformation {
+	suggested := make(map[string]TextTransformation, len(report.SuggestedTransforms))
+	for _, t := range report.SuggestedTransforms {
+		if t.ID == "" {
+			t.ID = transformationID(t)
+		}
+		suggested[t.ID] = t
+	}
+	return suggested
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Classes de conteúdo devolvidas por ClassifyContent
+const (
+	ContentText    = "text"
+	ContentBinary  = "binary"
+	ContentUnknown = "unknown"
+)
+
+// Status do relatório
+const (
+	StatusAnalyzed = "analyzed"
+	StatusSkipped  = "skipped"
+	// StatusPartial análise em fluxo interrompida por limite; os trechos já
+	// emitidos continuam válidos
+	StatusPartial = "partial"
+)
+
+// Limites da classificação: acima de maxNULRatio ou de maxControlRatio o
+// conteúdo é binário; entre suspectControlRatio e maxControlRatio é incerto
+const (
+	maxNULRatio         = 0.01
+	suspectControlRatio = 0.02
+	maxControlRatio     = 0.10
+)
+
+// ContentClassification resultado de ClassifyContent
+type ContentClassification struct {
+	Class  string `json:"class"`
+	Reason string `json:"reason"`
+	// Format formato reconhecido pelo número mágico, quando houver
+	Format string `json:"format,omitempty"`
+}
+
+// binarySignature número mágico de um formato binário comum
+type binarySignature struct {
+	format string
+	offset int
+	magic  []byte
+}
+
+var binarySignatures = []binarySignature{
+	{"pdf", 0, []byte("%PDF-")},
+	{"gzip", 0, []byte{0x1F, 0x8B}},
+	{"zip", 0, []byte("PK\x03\x04")},
+	{"zip", 0, []byte("PK\x05\x06")},
+	{"bzip2", 0, []byte("BZh")},
+	{"xz", 0, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}},
+	{"zstd", 0, []byte{0x28, 0xB5, 0x2F, 0xFD}},
+	{"7z", 0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}},
+	{"rar", 0, []byte("Rar!\x1A\x07")},
+	{"tar", 257, []byte("ustar")},
+	{"png", 0, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}},
+	{"jpeg", 0, []byte{0xFF, 0xD8, 0xFF}},
+	{"gif", 0, []byte("GIF8")},
+	{"webp", 8, []byte("WEBP")},
+	{"ico", 0, []byte{0x00, 0x00, 0x01, 0x00}},
+	{"elf", 0, []byte{0x7F, 'E', 'L', 'F'}},
+	{"pe", 0, []byte("MZ")},
+	{"mach-o", 0, []byte{0xCF, 0xFA, 0xED, 0xFE}},
+	{"mach-o", 0, []byte{0xCE, 0xFA, 0xED, 0xFE}},
+	{"java-class", 0, []byte{0xCA, 0xFE, 0xBA, 0xBE}},
+	{"wasm", 0, []byte("\x00asm")},
+	{"sqlite", 0, []byte("SQLite format 3\x00")},
+	{"ole2", 0, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}},
+	{"ogg", 0, []byte("OggS")},
+	{"mp3", 0, []byte("ID3")},
+	{"flac", 0, []byte("fLaC")},
+	{"mp4", 4, []byte("ftyp")},
+}
+
+// ClassifyContent classifica uma amostra do início do documento como texto,
+// binário ou incerto, pela ordem: BOM, número mágico, proporção de bytes NUL
+// e densidade de caracteres de controle
+func ClassifyContent(sample []byte) ContentClassification {
+	if len(sample) == 0 {
+		return ContentClassification{Class: ContentText, Reason: "empty"}
+	}
+	// BOMs primeiro: UTF-16 legítimo tem muitos NUL
+	if bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}) ||
+		bytes.HasPrefix(sample, []byte{0xFF, 0xFE}) ||
+		bytes.HasPrefix(sample, []byte{0xFE, 0xFF}) {
+		return ContentClassification{Class: ContentText, Reason: "bom"}
+	}
+
+	for _, signature := range binarySignatures {
+		end := signature.offset + len(signature.magic)
+		if len(sample) >= end && bytes.Equal(sample[signature.offset:end], signature.magic) {
+			// "MZ" e "ID3" são curtos demais para decidir sozinhos em texto puro
+			if (signature.format == "pe" || signature.format == "mp3") && utf8.Valid(sample) && bytes.IndexByte(sample, 0) < 0 {
+				continue
+			}
+			return ContentClassification{Class: ContentBinary, Reason: "magic_number", Format: signature.format}
+		}
+	}
+
+	nul := bytes.Count(sample, []byte{0})
+	if float64(nul)/float64(len(sample)) > maxNULRatio {
+		return ContentClassification{Class: ContentBinary, Reason: "nul_bytes"}
+	}
+
+	control := 0
+	for _, b := range sample {
+		if (b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != 0x1B) || b == 0x7F {
+			control++
+		}
+	}
+	ratio := float64(control) / float64(len(sample))
+	switch {
+	case ratio > maxControlRatio:
+		return ContentClassification{Class: ContentBinary, Reason: "control_characters"}
+	case ratio > suspectControlRatio || nul > 0:
+		return ContentClassification{Class: ContentUnknown, Reason: "control_characters"}
+	}
+	return ContentClassification{Class: ContentText, Reason: "printable"}
+}
+
+// classifySample classifica os primeiros sniffSampleSize bytes de data
+func classifySample(data []byte) ContentClassification {
+	if len(data) > sniffSampleSize {
+		data = data[:sniffSampleSize]
+	}
+	return ClassifyContent(data)
+}
@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+)
+
+// DefaultStreamChunkSize tamanho padrão dos trechos da análise em fluxo
+const DefaultStreamChunkSize = 4 << 20
+
+// minStreamChunkSize menor trecho aceito; abaixo disso o custo por trecho domina
+const minStreamChunkSize = 4 << 10
+
+// StreamOptions configuração da análise em fluxo
+type StreamOptions struct {
+	// ChunkSize bytes lidos por vez; 0 usa DefaultStreamChunkSize. A memória
+	// de pico fica em torno de duas vezes esse valor mais a análise do trecho.
+	ChunkSize int
+}
+
+// StreamChunk resultado de um trecho. As posições já são absolutas no
+// conteúdo convertido para UTF-8, como em Report.
+type StreamChunk struct {
+	Index               int                  `json:"index"`
+	Offset              int64                `json:"offset"`
+	Length              int                  `json:"length"`
+	Encoding            string               `json:"encoding"`
+	EncodingAnomalies   []EncodingAnomaly    `json:"encodingAnomalies"`
+	SuggestedTransforms []TextTransformation `json:"suggestedTransforms"`
+}
+
+// StreamSummary totais da análise em fluxo
+type StreamSummary struct {
+	DocumentPath         string                 `json:"documentPath"`
+	Status               string                 `json:"status"`
+	SkipReason           string                 `json:"skipReason,omitempty"`
+	ContentClass         *ContentClassification `json:"contentClass,omitempty"`
+	SourceCharacterSet   string                 `json:"sourceCharacterSet"`
+	InferredCharacterSet string                 `json:"inferredCharacterSet"`
+	AccuracyScore        float64                `json:"accuracyScore"`
+	Chunks               int                    `json:"chunks"`
+	BytesRead            int64                  `json:"bytesRead"`
+	Anomalies            int64                  `json:"anomalies"`
+	Corrections          int64                  `json:"corrections"`
+	Rejected             int64                  `json:"rejected"`
+	AnomaliesTruncated   bool                   `json:"anomaliesTruncated,omitempty"`
+	Warnings             []Warning              `json:"warnings,omitempty"`
+	EffectiveOptions     Options                `json:"effectiveOptions"`
+	AnalysisDuration     time.Duration          `json:"analysisDuration"`
+}
+
+// AnalyzeStream analisa r em trechos de tamanho limitado e chama visit com
+// as anomalias e correções de cada trecho assim que ele é processado. Os
+// trechos terminam em quebra de linha ou em espaço, de modo que nenhuma
+// sequência de mojibake ou palavra fique dividida entre dois trechos.
+func (e *Engine) AnalyzeStream(ctx context.Context, r io.Reader, options Options, stream StreamOptions, visit func(StreamChunk)) (*StreamSummary, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return e.analyzeStream(ctx, "", r, options, stream, visit)
+}
+
+// AnalyzeFileStream analisa o arquivo em fluxo, lendo-o pelo mapeamento em
+// memória quando disponível. As opções da configuração de projeto valem
+// aqui também, mas ignore não: o fluxo é sempre pedido para um arquivo só.
+func (e *Engine) AnalyzeFileStream(ctx context.Context, path string, options Options, stream StreamOptions, visit func(StreamChunk)) (*StreamSummary, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	options, _, projectErr := e.OptionsForPath(path, options)
+	if projectErr != nil {
+		return nil, projectErr
+	}
+
+	mapped, openErr := OpenMapped(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer mapped.Close()
+
+	return e.analyzeStream(ctx, path, mapped.Reader(), options, stream, visit)
+}
+
+// AnalyzeRange analisa apenas os bytes [offset, offset+length) de um arquivo
+// mapeado. As posições do relatório são relativas ao início do trecho.
+func (e *Engine) AnalyzeRange(ctx context.Context, mapped *MappedFile, offset, length int64, options Options) (*Report, error) {
+	release, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	data, sliceErr := mapped.slice(offset, length)
+	if sliceErr != nil {
+		return nil, sliceErr
+	}
+	return e.analyzeContent(ctx, mapped.file.Name(), data, options)
+}
+
+func (e *Engine) analyzeStream(ctx context.Context, path string, r io.Reader, options Options, stream StreamOptions, visit func(StreamChunk)) (*StreamSummary, error) {
+	chunkSize := stream.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize < minStreamChunkSize {
+		return nil, NewError(ErrCodeInvalidArgument, "stream chunk size too small", map[string]interface{}{"chunkSize": chunkSize, "minimum": minStreamChunkSize})
+	}
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+
+	// timeout_ms vale para o fluxo inteiro; max_file_size não se aplica
+	ctx, cancel := withAnalysisTimeout(ctx, options)
+	defer cancel()
+
+	startTime := time.Now()
+	summary := &StreamSummary{
+		DocumentPath:         path,
+		Status:               StatusAnalyzed,
+		InferredCharacterSet: "UTF-8",
+		EffectiveOptions:     options,
+	}
+	var scoreTotal float64
+	var offset int64
+	recorded := 0
+
+	// O buffer guarda o resto não analisado do trecho anterior (no máximo
+	// chunkSize bytes) seguido do trecho novo
+	buffer := make([]byte, 2*chunkSize)
+	pending := 0
+	for {
+		n, readErr := io.ReadFull(r, buffer[pending:pending+chunkSize])
+		summary.BytesRead += int64(n)
+		eof := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !eof {
+			return nil, NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "offset": summary.BytesRead, "cause": readErr.Error()})
+		}
+		total := pending + n
+		if total == 0 {
+			break
+		}
+
+		// A classificação usa o início do primeiro trecho; binários param aqui
+		if summary.Chunks == 0 {
+			class := classifySample(buffer[:total])
+			summary.ContentClass = &class
+			if class.Class == ContentBinary {
+				summary.Status = StatusSkipped
+				summary.SkipReason = ContentBinary
+				break
+			}
+		}
+
+		cut := total
+		if !eof {
+			cut = streamChunkCut(buffer[:total], total-chunkSize)
+		}
+
+		content, encoding := decodeDocument(buffer[:cut])
+		report, analyzeErr := e.analyzeText(ctx, path, content, encoding, options)
+		if analyzeErr != nil {
+			if timedOut(ctx) {
+				summary.Status = StatusPartial
+				summary.SkipReason = skipReasonTimeout
+				summary.Warnings = append(summary.Warnings, timeoutWarning(options))
+				break
+			}
+			return nil, analyzeErr
+		}
+
+		// max_anomalies vale para o fluxo inteiro: depois dele os trechos só contam
+		if options.MaxAnomalies > 0 {
+			room := options.MaxAnomalies - recorded
+			if room < 0 {
+				room = 0
+			}
+			if len(report.EncodingAnomalies) > room {
+				report.EncodingAnomalies = report.EncodingAnomalies[:room]
+			}
+		}
+		recorded += len(report.EncodingAnomalies)
+		if len(report.EncodingAnomalies) < report.AnomalyCount {
+			summary.AnomaliesTruncated = true
+		}
+
+		for i := range report.EncodingAnomalies {
+			report.EncodingAnomalies[i].TextPosition += int(offset)
+		}
+		for i := range report.SuggestedTransforms {
+			report.SuggestedTransforms[i].DocumentPosition += int(offset)
+			scoreTotal += report.SuggestedTransforms[i].TransformationScore
+		}
+		summary.SourceCharacterSet = mergeStreamEncoding(summary.SourceCharacterSet, encoding)
+		summary.Anomalies += int64(report.AnomalyCount)
+		summary.Corrections += int64(len(report.SuggestedTransforms))
+		summary.Rejected += int64(len(report.RejectedTransforms))
+		if visit != nil {
+			visit(StreamChunk{
+				Index:               summary.Chunks,
+				Offset:              offset,
+				Length:              len(content),
+				Encoding:            encoding,
+				EncodingAnomalies:   report.EncodingAnomalies,
+				SuggestedTransforms: report.SuggestedTransforms,
+			})
+		}
+		summary.Chunks++
+		offset += int64(len(content))
+
+		pending = copy(buffer, buffer[cut:total])
+		if eof && pending == 0 {
+			break
+		}
+	}
+
+	if summary.AnomaliesTruncated {
+		summary.Warnings = append(summary.Warnings, anomaliesWarning(summary.Anomalies, options))
+	}
+	summary.AccuracyScore = streamConfidence(summary.Anomalies, summary.Corrections, scoreTotal)
+	summary.AnalysisDuration = time.Since(startTime)
+	return summary, nil
+}
+
+// streamChunkCut escolhe onde terminar o trecho: na última quebra de linha,
+// senão no último espaço precedido de caractere ASCII (nenhuma sequência de
+// mojibake contém esse par), senão no último limite de caractere UTF-8. O
+// corte nunca fica antes de minCut, o que limita o resto pendente.
+func streamChunkCut(data []byte, minCut int) int {
+	if minCut < 1 {
+		minCut = 1
+	}
+	if i := bytes.LastIndexByte(data[minCut-1:], '\n'); i >= 0 {
+		return minCut + i
+	}
+	for i := len(data) - 1; i >= minCut && i >= 1; i-- {
+		if (data[i] == ' ' || data[i] == '\t') && data[i-1] < 0x80 {
+			return i + 1
+		}
+	}
+	if cut := incompleteRuneStart(data); cut >= minCut {
+		return cut
+	}
+	return len(data)
+}
+
+// mergeStreamEncoding combina o encoding de um trecho com o dos anteriores.
+// Trechos só ASCII são compatíveis com qualquer outro.
+func mergeStreamEncoding(current, chunk string) string {
+	switch {
+	case current == "" || current == "ASCII":
+		return chunk
+	case chunk == "ASCII" || chunk == current:
+		return current
+	}
+	return "mixed"
+}
+
+// streamConfidence mesma fórmula de calculateConfidence, a partir dos totais
+func streamConfidence(anomalies, corrections int64, scoreTotal float64) float64 {
+	if anomalies == 0 {
+		return 1.0
+	}
+	if corrections == 0 {
+		return 0.0
+	}
+	ratio := float64(corrections) / float64(anomalies)
+	if ratio > 1.0 {
+		ratio = 1.0
+	}
+	return scoreTotal / float64(corrections) * ratio
+}
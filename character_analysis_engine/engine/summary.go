@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultSummaryExamples quantidade de exemplos de anomalias e correções no resumo
+const DefaultSummaryExamples = 5
+
+// Paginação das consultas sobre um relatório
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// Tipos de item consultáveis em um relatório
+const (
+	QueryAnomalies       = "anomalies"
+	QueryTransformations = "transformations"
+	QueryRejected        = "rejected"
+)
+
+// ReportSummary visão compacta de um relatório: contagens por categoria,
+// severidade e estratégia e alguns exemplos, sem as listas completas
+type ReportSummary struct {
+	DocumentPath          string               `json:"documentPath"`
+	Status                string               `json:"status"`
+	SkipReason            string               `json:"skipReason,omitempty"`
+	SourceCharacterSet    string               `json:"sourceCharacterSet"`
+	InferredCharacterSet  string               `json:"inferredCharacterSet"`
+	AccuracyScore         float64              `json:"accuracyScore"`
+	AnomalyCount          int                  `json:"anomalyCount"`
+	AnomaliesTruncated    bool                 `json:"anomaliesTruncated,omitempty"`
+	TransformationCount   int                  `json:"transformationCount"`
+	RejectedCount         int                  `json:"rejectedCount"`
+	ByCategory            map[string]int       `json:"byCategory"`
+	BySeverity            map[string]int       `json:"bySeverity"`
+	ByStrategy            map[string]int       `json:"byStrategy"`
+	TopAnomalies          []EncodingAnomaly    `json:"topAnomalies"`
+	TopTransforms         []TextTransformation `json:"topTransforms"`
+	Warnings              []Warning            `json:"warnings,omitempty"`
+	AnalysisDuration      time.Duration        `json:"analysisDuration"`
+	TransformationSuccess bool                 `json:"transformationSuccess"`
+}
+
+// severityRank ordem dos exemplos no resumo: mais graves primeiro
+var severityRank = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+// Summary resume o relatório com até examples exemplos de cada lista
+// (0 usa DefaultSummaryExamples). As contagens por categoria e severidade
+// cobrem todas as anomalias encontradas, mesmo quando a lista foi truncada.
+func (r *Report) Summary(examples int) *ReportSummary {
+	if examples <= 0 {
+		examples = DefaultSummaryExamples
+	}
+	summary := &ReportSummary{
+		DocumentPath:          r.DocumentPath,
+		Status:                r.Status,
+		SkipReason:            r.SkipReason,
+		SourceCharacterSet:    r.SourceCharacterSet,
+		InferredCharacterSet:  r.InferredCharacterSet,
+		AccuracyScore:         r.AccuracyScore,
+		AnomalyCount:          r.AnomalyCount,
+		AnomaliesTruncated:    r.AnomaliesTruncated,
+		TransformationCount:   len(r.SuggestedTransforms),
+		RejectedCount:         len(r.RejectedTransforms),
+		ByCategory:            map[string]int{},
+		BySeverity:            map[string]int{},
+		ByStrategy:            map[string]int{},
+		Warnings:              r.Warnings,
+		AnalysisDuration:      r.AnalysisDuration,
+		TransformationSuccess: r.TransformationSuccess,
+	}
+
+	// Relatórios sem totais (ex.: trechos de stream) contam a lista registrada
+	totals := r.AnomalyTotals
+	if totals == nil {
+		tally := anomalyTally{}
+		for _, anomaly := range r.EncodingAnomalies {
+			tally.add(anomaly.AnomalyCategory, anomaly.SeverityLevel, 1)
+		}
+		totals = tally.totals()
+	}
+	for _, total := range totals {
+		summary.ByCategory[total.Category] += total.Count
+		summary.BySeverity[total.Severity] += total.Count
+	}
+	for _, transformation := range r.SuggestedTransforms {
+		summary.ByStrategy[transformation.TextTransformationStrategy]++
+	}
+
+	anomalies := make([]EncodingAnomaly, len(r.EncodingAnomalies))
+	copy(anomalies, r.EncodingAnomalies)
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return rankSeverity(anomalies[i].SeverityLevel) < rankSeverity(anomalies[j].SeverityLevel)
+	})
+	summary.TopAnomalies = anomalies[:min(examples, len(anomalies))]
+
+	transforms := make([]TextTransformation, len(r.SuggestedTransforms))
+	copy(transforms, r.SuggestedTransforms)
+	sort.SliceStable(transforms, func(i, j int) bool {
+		return transforms[i].TransformationScore > transforms[j].TransformationScore
+	})
+	summary.TopTransforms = transforms[:min(examples, len(transforms))]
+	return summary
+}
+
+func rankSeverity(severity string) int {
+	if rank, ok := severityRank[severity]; ok {
+		return rank
+	}
+	return len(severityRank)
+}
+
+// ReportQuery consulta paginada sobre uma das listas do relatório. Os
+// filtros vazios aceitam qualquer valor; Category e Severity valem para
+// anomalias e Strategy para correções sugeridas e rejeitadas.
+type ReportQuery struct {
+	Kind     string `json:"kind"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Strategy string `json:"strategy"`
+}
+
+// ReportPage uma página da consulta. Total conta os itens que passam nos
+// filtros, não apenas os desta página.
+type ReportPage struct {
+	Kind    string      `json:"kind"`
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	Total   int         `json:"total"`
+	HasMore bool        `json:"hasMore"`
+	Items   interface{} `json:"items"`
+}
+
+// Query devolve uma página de anomalias, correções ou rejeições filtradas
+func (r *Report) Query(query ReportQuery) (*ReportPage, error) {
+	if query.Kind == "" {
+		query.Kind = QueryAnomalies
+	}
+	if query.Offset < 0 || query.Limit < 0 {
+		return nil, NewError(ErrCodeInvalidArgument, "offset and limit must not be negative", map[string]interface{}{"offset": query.Offset, "limit": query.Limit})
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.Limit > MaxPageSize {
+		query.Limit = MaxPageSize
+	}
+
+	page := &ReportPage{Kind: query.Kind, Offset: query.Offset, Limit: query.Limit}
+	switch query.Kind {
+	case QueryAnomalies:
+		if query.Strategy != "" {
+			return nil, errQueryFilter(query.Kind, "strategy")
+		}
+		var matched []EncodingAnomaly
+		for _, anomaly := range r.EncodingAnomalies {
+			if matchesFilter(query.Category, anomaly.AnomalyCategory) && matchesFilter(query.Severity, anomaly.SeverityLevel) {
+				matched = append(matched, anomaly)
+			}
+		}
+		start, end := pageBounds(len(matched), query)
+		page.Total, page.Items = len(matched), append([]EncodingAnomaly{}, matched[start:end]...)
+	case QueryTransformations:
+		if query.Category != "" || query.Severity != "" {
+			return nil, errQueryFilter(query.Kind, "category/severity")
+		}
+		var matched []TextTransformation
+		for _, transformation := range r.SuggestedTransforms {
+			if matchesFilter(query.Strategy, transformation.TextTransformationStrategy) {
+				matched = append(matched, transformation)
+			}
+		}
+		start, end := pageBounds(len(matched), query)
+		page.Total, page.Items = len(matched), append([]TextTransformation{}, matched[start:end]...)
+	case QueryRejected:
+		if query.Category != "" || query.Severity != "" {
+			return nil, errQueryFilter(query.Kind, "category/severity")
+		}
+		var matched []RejectedTransformation
+		for _, rejected := range r.RejectedTransforms {
+			if matchesFilter(query.Strategy, rejected.Transformation.TextTransformationStrategy) {
+				matched = append(matched, rejected)
+			}
+		}
+		start, end := pageBounds(len(matched), query)
+		page.Total, page.Items = len(matched), append([]RejectedTransformation{}, matched[start:end]...)
+	default:
+		return nil, NewError(ErrCodeInvalidArgument, "unknown query kind", map[string]interface{}{
+			"kind":    query.Kind,
+			"allowed": []string{QueryAnomalies, QueryTransformations, QueryRejected},
+		})
+	}
+	page.HasMore = query.Offset+query.Limit < page.Total
+	return page, nil
+}
+
+func matchesFilter(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+// pageBounds recorta [offset, offset+limit) dentro de n itens
+func pageBounds(n int, query ReportQuery) (int, int) {
+	start := min(query.Offset, n)
+	return start, min(start+query.Limit, n)
+}
+
+func errQueryFilter(kind, filter string) *Error {
+	return NewError(ErrCodeInvalidArgument, "filter does not apply to this query kind", map[string]interface{}{"kind": kind, "filter": filter})
+}
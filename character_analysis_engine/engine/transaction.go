@@ -0,0 +1,447 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Estados de uma transação
+const (
+	TransactionCommitting = "committing"
+	TransactionCommitted  = "committed"
+	TransactionRolledBack = "rolled_back"
+	TransactionUndone     = "undone"
+)
+
+// transactionManifest arquivo com o estado da transação, em <dir>/<id>/
+const transactionManifest = "transaction.json"
+
+// TransactionFile arquivo reescrito por uma transação. Resolution é
+// preenchido por rollback e UndoTransaction (rolled_back ou conflict).
+type TransactionFile struct {
+	Path         string `json:"path"`
+	OriginalHash string `json:"originalHash"`
+	FixedHash    string `json:"fixedHash"`
+	Backup       string `json:"backup"`
+	Corrections  int    `json:"corrections"`
+	Resolution   string `json:"resolution,omitempty"`
+}
+
+// Transaction correção de vários arquivos aplicada como uma unidade: todos
+// são reescritos ou nenhum é. Os originais ficam em <dir>/<id>/backups até
+// UndoTransaction.
+type Transaction struct {
+	ID        string            `json:"transactionId"`
+	Created   time.Time         `json:"created"`
+	State     string            `json:"state"`
+	Files     []TransactionFile `json:"files"`
+	Unchanged []string          `json:"unchanged"`
+	Skipped   []SkippedFile     `json:"skipped,omitempty"`
+	Error     *Error            `json:"error,omitempty"`
+
+	dir string
+	// transforms correções de cada arquivo preparado, para o log de auditoria
+	transforms map[string][]TextTransformation
+	// options opções efetivas de cada arquivo preparado, já com as do projeto
+	options map[string]Options
+}
+
+// ApplyTransaction corrige paths em três fases. Na preparação cada arquivo é
+// analisado no pool e o texto corrigido e o original são gravados no
+// diretório da transação, sem tocar nos documentos. Na verificação o texto
+// preparado é relido e conferido (hash e UTF-8 válido) e os originais não
+// podem ter mudado. Só então os documentos são substituídos; se uma escrita
+// falhar, os já substituídos voltam ao original e a transação fica
+// rolled_back. Uma falha na preparação ou na verificação não deixa rastro.
+func (e *Engine) ApplyTransaction(ctx context.Context, dir string, paths []string, options Options) (*Transaction, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+
+	if dir == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "transaction directory not configured", nil)
+	}
+	if err := options.validate(); err != nil {
+		return nil, NewError(ErrCodeInvalidOptions, err.Error(), nil)
+	}
+	// Caminhos absolutos: UndoTransaction pode rodar em outro diretório
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	absPaths := make([]string, len(paths))
+	for i, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, NewError(ErrCodeInvalidPath, "failed to resolve document path", map[string]interface{}{"path": path, "cause": err.Error()})
+		}
+		absPaths[i] = absPath
+	}
+	created := time.Now().UTC()
+	id, err := newRecordID(created)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Transaction{ID: id, Created: created, State: TransactionCommitting, Files: []TransactionFile{}, Unchanged: []string{}, dir: dir, transforms: map[string][]TextTransformation{}, options: map[string]Options{}}
+	if err := os.MkdirAll(tx.path(), 0o755); err != nil {
+		return nil, NewError(ErrCodeIO, "failed to create transaction directory", map[string]interface{}{"dir": tx.path(), "cause": err.Error()})
+	}
+
+	if err := e.stageTransaction(ctx, tx, absPaths, options); err != nil {
+		os.RemoveAll(tx.path())
+		return nil, err
+	}
+	sort.Slice(tx.Files, func(a, b int) bool { return tx.Files[a].Path < tx.Files[b].Path })
+	sort.Strings(tx.Unchanged)
+	for _, file := range tx.Files {
+		if err := tx.verify(file); err != nil {
+			os.RemoveAll(tx.path())
+			return nil, err
+		}
+	}
+
+	// O manifesto vai para o disco antes da primeira escrita: depois de uma
+	// queda, UndoTransaction encontra a transação em committing
+	if err := tx.save(); err != nil {
+		os.RemoveAll(tx.path())
+		return nil, err
+	}
+	for _, file := range tx.Files {
+		if err := e.commitFile(tx, file); err != nil {
+			tx.Error = AsError(err, ErrCodeIO)
+			conflicts, auditErr := tx.restore(e.audit, AuditRollback)
+			tx.State = TransactionRolledBack
+			os.RemoveAll(filepath.Join(tx.path(), "staged"))
+			if saveErr := tx.save(); saveErr != nil {
+				return tx, saveErr
+			}
+			if auditErr != nil {
+				return tx, auditErr
+			}
+			details := map[string]interface{}{"transactionId": tx.ID, "path": file.Path, "cause": tx.Error.Message}
+			if len(conflicts) > 0 {
+				details["conflicts"] = conflicts
+			}
+			return tx, NewError(ErrCodeIO, "transaction rolled back", details)
+		}
+	}
+	tx.State = TransactionCommitted
+	os.RemoveAll(filepath.Join(tx.path(), "staged"))
+	if err := tx.save(); err != nil {
+		return tx, err
+	}
+	return tx, nil
+}
+
+// stageTransaction prepara os arquivos no pool da instância. A primeira
+// falha cancela a preparação dos demais.
+func (e *Engine) stageTransaction(ctx context.Context, tx *Transaction, paths []string, options Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	e.totalFiles.Store(int64(len(paths)))
+	e.processing.Store(0)
+
+	var batch sync.WaitGroup
+	var lock sync.Mutex
+	var firstErr error
+	for _, path := range paths {
+		path := path
+		submitErr := e.submitTask(ctx, &batch, func(taskCtx context.Context) {
+			if taskCtx.Err() != nil {
+				return
+			}
+			err := e.stageFile(taskCtx, tx, path, options, &lock)
+			if err != nil {
+				lock.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				lock.Unlock()
+			}
+		})
+		if submitErr != nil {
+			batch.Wait()
+			return submitErr
+		}
+	}
+	batch.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return NewError(ErrCodeCancelled, "transaction cancelled before commit", nil)
+	}
+	return nil
+}
+
+// stageFile corrige um arquivo e grava o original e o texto corrigido no
+// diretório da transação
+func (e *Engine) stageFile(ctx context.Context, tx *Transaction, path string, options Options, lock *sync.Mutex) error {
+	skip := func(reason, detail string) {
+		lock.Lock()
+		tx.Skipped = append(tx.Skipped, SkippedFile{Path: path, Reason: reason, Detail: detail})
+		lock.Unlock()
+	}
+	options, effective, err := e.OptionsForPath(path, options)
+	if err != nil {
+		return err
+	}
+	if effective != nil && effective.Ignored {
+		skip(skipReasonIgnored, "ignored by "+effective.IgnoredBy)
+		return nil
+	}
+	if options.MaxFileSize > 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() > options.MaxFileSize {
+			skip(skipReasonTooLarge, fileSizeWarning(options).Message)
+			return nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to read document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	fixed, report, err := e.fixContent(ctx, data, options)
+	if err != nil {
+		if AsError(err, ErrCodeInternal).Code == ErrCodeLimitExceeded {
+			skip(skipReasonTimeout, timeoutWarning(options).Message)
+			return nil
+		}
+		return err
+	}
+	if report.Status == StatusSkipped {
+		skip(report.SkipReason, "")
+		return nil
+	}
+	if len(report.SuggestedTransforms) == 0 || fixed == string(data) {
+		lock.Lock()
+		tx.Unchanged = append(tx.Unchanged, path)
+		lock.Unlock()
+		return nil
+	}
+
+	file := TransactionFile{
+		Path:         path,
+		OriginalHash: hashContent(data),
+		FixedHash:    hashContent([]byte(fixed)),
+		Corrections:  len(report.SuggestedTransforms),
+	}
+	file.Backup = tx.backupPath(file)
+	if err := writeFileAtomic(file.Backup, data); err != nil {
+		return NewError(ErrCodeIO, "failed to write transaction backup", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	if err := writeFileAtomic(tx.stagedPath(file), []byte(fixed)); err != nil {
+		return NewError(ErrCodeIO, "failed to stage document", map[string]interface{}{"path": path, "cause": err.Error()})
+	}
+	lock.Lock()
+	tx.Files = append(tx.Files, file)
+	tx.transforms[path] = report.SuggestedTransforms
+	tx.options[path] = options
+	lock.Unlock()
+	return nil
+}
+
+// verify confere o texto preparado e se o documento continua como foi lido
+func (tx *Transaction) verify(file TransactionFile) error {
+	staged, err := os.ReadFile(tx.stagedPath(file))
+	if err != nil || hashContent(staged) != file.FixedHash || !utf8.Valid(staged) {
+		return NewError(ErrCodeIO, "staged document failed verification", map[string]interface{}{"path": file.Path})
+	}
+	current, err := os.ReadFile(file.Path)
+	if err != nil || hashContent(current) != file.OriginalHash {
+		return NewError(ErrCodeIO, "document changed while the transaction was being prepared", map[string]interface{}{"path": file.Path})
+	}
+	return nil
+}
+
+// commitFile substitui o documento pelo texto preparado, com as opções com
+// que ele foi preparado. O original já está nos backups da transação, então
+// não há entrada no repositório de backups. Um documento que mudou depois de
+// verify não é tocado e a transação volta atrás.
+func (e *Engine) commitFile(tx *Transaction, file TransactionFile) error {
+	options := tx.options[file.Path]
+	options.BackupFiles = false
+	staged, err := os.ReadFile(tx.stagedPath(file))
+	if err != nil {
+		return NewError(ErrCodeIO, "failed to read staged document", map[string]interface{}{"path": file.Path, "cause": err.Error()})
+	}
+	return e.applyFixed(AuditTransaction, file.Path, file.OriginalHash, string(staged), tx.transforms[file.Path], options)
+}
+
+// restore devolve ao original os arquivos que estão com o texto corrigido.
+// Arquivos ainda com o original ficam como estão; os alterados por fora
+// não são tocados e ficam como conflict. Cada restauração é registrada em
+// audit como operation antes da troca. Retorna os conflitos e o primeiro
+// erro de escrita ou de registro; o arquivo afetado também fica como conflict.
+func (tx *Transaction) restore(audit *AuditLog, operation string) ([]string, error) {
+	var conflicts []string
+	var restoreErr error
+	for i := range tx.Files {
+		file := &tx.Files[i]
+		current, err := os.ReadFile(file.Path)
+		if err == nil && hashContent(current) == file.OriginalHash {
+			// Nunca reescrito, ou um conflito que o usuário já desfez
+			if file.Resolution == FixResolutionConflict {
+				file.Resolution = ""
+			}
+			continue
+		}
+		if err != nil || hashContent(current) != file.FixedHash {
+			file.Resolution = FixResolutionConflict
+			conflicts = append(conflicts, file.Path)
+			continue
+		}
+		original, err := os.ReadFile(tx.backupPath(*file))
+		if err != nil || hashContent(original) != file.OriginalHash {
+			file.Resolution = FixResolutionConflict
+			conflicts = append(conflicts, file.Path)
+			continue
+		}
+		if err := writeRecorded(audit, operation, file.Path, current, string(original), nil, nil, ""); err != nil {
+			if restoreErr == nil {
+				restoreErr = err
+			}
+			file.Resolution = FixResolutionConflict
+			conflicts = append(conflicts, file.Path)
+			continue
+		}
+		file.Resolution = FixResolutionRolledBack
+	}
+	return conflicts, restoreErr
+}
+
+// UndoTransaction desfaz uma transação concluída, ou interrompida por uma
+// queda durante a escrita, restaurando os originais guardados. Arquivos
+// alterados depois da transação não são tocados e são listados no erro; a
+// transação continua desfazível e uma nova chamada, depois de resolvidos os
+// conflitos, restaura só o que falta. As restaurações são registradas em
+// audit, se não for nil.
+func UndoTransaction(dir, id string, audit *AuditLog) (*Transaction, error) {
+	tx, err := LoadTransaction(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.State != TransactionCommitted && tx.State != TransactionCommitting {
+		return tx, NewError(ErrCodeInvalidArgument, "transaction is already "+tx.State, map[string]interface{}{"transactionId": id})
+	}
+	conflicts, auditErr := tx.restore(audit, AuditUndo)
+	if len(conflicts) == 0 && auditErr == nil {
+		tx.State = TransactionUndone
+	}
+	if err := tx.save(); err != nil {
+		return tx, err
+	}
+	if auditErr != nil {
+		return tx, auditErr
+	}
+	if len(conflicts) > 0 {
+		return tx, NewError(ErrCodeIO, "some documents changed after the transaction and were left untouched", map[string]interface{}{"transactionId": id, "conflicts": conflicts})
+	}
+	return tx, nil
+}
+
+// UndoTransaction como a função UndoTransaction, sobre o diretório de
+// transações e o log de auditoria da instância. Falha com engine_not_running
+// depois de Shutdown.
+func (e *Engine) UndoTransaction(id string) (*Transaction, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	return UndoTransaction(e.config.TransactionDirectory, id, e.audit)
+}
+
+// ListTransactions lista as transações do diretório configurado na instância
+func (e *Engine) ListTransactions() ([]*Transaction, error) {
+	release, acquireErr := e.acquire()
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
+	defer release()
+	if e.config.TransactionDirectory == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "transaction directory not configured", nil)
+	}
+	return ListTransactions(e.config.TransactionDirectory)
+}
+
+// LoadTransaction lê o manifesto de uma transação
+func LoadTransaction(dir, id string) (*Transaction, error) {
+	if dir == "" {
+		return nil, NewError(ErrCodeInvalidArgument, "transaction directory not configured", nil)
+	}
+	if !recordIDPattern.MatchString(id) {
+		return nil, NewError(ErrCodeInvalidArgument, "invalid transaction id", map[string]interface{}{"transactionId": id})
+	}
+	tx := &Transaction{ID: id, dir: dir}
+	data, err := os.ReadFile(filepath.Join(tx.path(), transactionManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, NewError(ErrCodeInvalidArgument, "unknown transaction", map[string]interface{}{"transactionId": id})
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read transaction", map[string]interface{}{"transactionId": id, "cause": err.Error()})
+	}
+	if err := json.Unmarshal(data, tx); err != nil {
+		return nil, NewError(ErrCodeSerialization, "transaction manifest is unreadable", map[string]interface{}{"transactionId": id, "cause": err.Error()})
+	}
+	tx.dir = dir
+	return tx, nil
+}
+
+// ListTransactions lista as transações de dir, mais recentes primeiro
+func ListTransactions(dir string) ([]*Transaction, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Transaction{}, nil
+	}
+	if err != nil {
+		return nil, NewError(ErrCodeIO, "failed to read transaction directory", map[string]interface{}{"dir": dir, "cause": err.Error()})
+	}
+	transactions := []*Transaction{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tx, err := LoadTransaction(dir, entry.Name())
+		if err != nil {
+			continue
+		}
+		transactions = append(transactions, tx)
+	}
+	sort.Slice(transactions, func(a, b int) bool { return transactions[a].Created.After(transactions[b].Created) })
+	return transactions, nil
+}
+
+// save grava o manifesto de forma atômica
+func (tx *Transaction) save() error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return NewError(ErrCodeSerialization, err.Error(), nil)
+	}
+	if err := writeFileAtomic(filepath.Join(tx.path(), transactionManifest), data); err != nil {
+		return NewError(ErrCodeIO, "failed to write transaction manifest", map[string]interface{}{"transactionId": tx.ID, "cause": err.Error()})
+	}
+	return nil
+}
+
+func (tx *Transaction) path() string {
+	return filepath.Join(tx.dir, tx.ID)
+}
+
+func (tx *Transaction) backupPath(file TransactionFile) string {
+	return filepath.Join(tx.path(), "backups", file.OriginalHash)
+}
+
+func (tx *Transaction) stagedPath(file TransactionFile) string {
+	return filepath.Join(tx.path(), "staged", file.FixedHash)
+}
@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// applyIntelligentTextTransformations gera as correções candidatas de todas as
+// estratégias habilitadas. O chamador deve manter dictionaryLock em leitura.
+// Quando check indica cancelamento o resultado é parcial e deve ser descartado.
+func (e *Engine) applyIntelligentTextTransformations(check *cancelCheck, content string, options Options) []TextTransformation {
+	var corrections []TextTransformation
+
+	if options.FixMojibake {
+		corrections = append(corrections, e.findMojibakeTableTransformations(check, content)...)
+	}
+
+	// Correções contextuais usando dicionário
+	if options.UseDictionary {
+		cutoff := options.similarityCutoff()
+		for _, span := range splitWordSpans(content) {
+			if check.cancelled() {
+				break
+			}
+			cleanWord := strings.ToLower(span.text)
+			if !e.dictTrie.SearchVocabulary(cleanWord) && !options.vocabulary[cleanWord] && len(cleanWord) > 2 {
+				// Tenta encontrar palavra similar no dicionário
+				if suggestion := findSimilarWord(cleanWord, cutoff); suggestion != "" {
+					confidence := clampScore(calculateSimilarity(cleanWord, suggestion) + e.learnedAdjustment("similarity", content, span.position, span.text, suggestion))
+					corrections = append(corrections, TextTransformation{
+						DocumentPosition:           span.position,
+						OriginalSequence:           span.text,
+						TransformedSequence:        suggestion,
+						TransformationScore:        confidence,
+						TextTransformationStrategy: "similarity",
+					})
+				}
+			}
+		}
+	}
+
+	// Estratégias arriscadas só rodam no modo agressivo
+	if options.AggressiveMode {
+		corrections = append(corrections, e.findRedecodeTransformations(check, content)...)
+	}
+
+	return corrections
+}
+
+// findMojibakeTableTransformations estratégia "dictionary": tabela fixa de mojibake UTF-8 lido como Latin-1
+func (e *Engine) findMojibakeTableTransformations(check *cancelCheck, content string) []TextTransformation {
+	var corrections []TextTransformation
+
+	// Correções baseadas em dicionário
+	mojibakeMap := map[string]string{
+		"Ã¡": "á", "Ã ": "à", "Ã£": "ã", "Ã¢": "â",
+		"Ã©": "é", "Ã¨": "è", "Ãª": "ê",
+		"Ã­": "í", "Ã¬": "ì", "Ã®": "î",
+		"Ã³": "ó", "Ã²": "ò", "Ãµ": "õ", "Ã´": "ô",
+		"Ãº": "ú", "Ã¹": "ù", "Ã»": "û",
+		"Ã§": "ç", "Ã±": "ñ",
+	}
+
+	for broken, correct := range mojibakeMap {
+		pos := 0
+		for !check.cancelled() {
+			index := strings.Index(content[pos:], broken)
+			if index == -1 {
+				break
+			}
+			actualPos := pos + index
+
+			// Verifica contexto usando n-gramas
+			confidence := e.calculateTextTransformationConfidence(content, actualPos, broken, correct, "dictionary")
+
+			corrections = append(corrections, TextTransformation{
+				DocumentPosition:           actualPos,
+				OriginalSequence:           broken,
+				TransformedSequence:        correct,
+				TransformationScore:        confidence,
+				TextTransformationStrategy: "dictionary",
+			})
+			pos = actualPos + len(broken)
+		}
+	}
+
+	return corrections
+}
+
+// findRedecodeTransformations estratégia "pattern": re-decodifica qualquer
+// sequência de caracteres Latin-1/Windows-1252 cujos bytes formem UTF-8 válido.
+// Cobre casos fora da tabela fixa, ao custo de mais falsos positivos.
+func (e *Engine) findRedecodeTransformations(check *cancelCheck, content string) []TextTransformation {
+	var corrections []TextTransformation
+
+	type runeByte struct {
+		offset int
+		size   int
+		value  byte
+	}
+	var run []runeByte
+
+	flush := func() {
+		j := 0
+		for j < len(run) {
+			groupStart := j
+			var decoded []byte
+			for j < len(run) {
+				n := utf8SequenceLength(run[j].value)
+				if n < 2 || j+n > len(run) {
+					break
+				}
+				sequence := make([]byte, n)
+				for k := 0; k < n; k++ {
+					sequence[k] = run[j+k].value
+				}
+				if !utf8.Valid(sequence) {
+					break
+				}
+				decoded = append(decoded, sequence...)
+				j += n
+			}
+			if len(decoded) == 0 {
+				j++
+				continue
+			}
+			start := run[groupStart].offset
+			end := run[j-1].offset + run[j-1].size
+			original := content[start:end]
+			confidence := e.calculateTextTransformationConfidence(content, start, original, string(decoded), "pattern") - 0.1
+			corrections = append(corrections, TextTransformation{
+				DocumentPosition:           start,
+				OriginalSequence:           original,
+				TransformedSequence:        string(decoded),
+				TransformationScore:        confidence,
+				TextTransformationStrategy: "pattern",
+			})
+		}
+		run = run[:0]
+	}
+
+	for i, r := range content {
+		if check.cancelled() {
+			break
+		}
+		if b, ok := singleByteValue(r); ok && b >= 0x80 {
+			run = append(run, runeByte{offset: i, size: utf8.RuneLen(r), value: b})
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	return corrections
+}
+
+// utf8SequenceLength retorna o tamanho da sequência UTF-8 iniciada por lead, ou 0
+func utf8SequenceLength(lead byte) int {
+	switch {
+	case lead >= 0xC2 && lead <= 0xDF:
+		return 2
+	case lead >= 0xE0 && lead <= 0xEF:
+		return 3
+	case lead >= 0xF0 && lead <= 0xF4:
+		return 4
+	}
+	return 0
+}
+
+// filterByConfidence separa as correções abaixo do limiar de confiança
+func filterByConfidence(candidates []TextTransformation, threshold float64) ([]TextTransformation, []RejectedTransformation) {
+	var kept []TextTransformation
+	var rejected []RejectedTransformation
+	for _, candidate := range candidates {
+		if candidate.TransformationScore < threshold {
+			rejected = append(rejected, RejectedTransformation{
+				Transformation:  candidate,
+				RejectionReason: rejectionBelowThreshold,
+			})
+			continue
+		}
+		kept = append(kept, candidate)
+	}
+	return kept, rejected
+}
+
+// wordSpan é uma palavra do documento com seu offset real em bytes
+type wordSpan struct {
+	position int
+	text     string
+}
+
+// splitWordSpans separa o conteúdo em palavras sem a pontuação das bordas,
+// preservando o offset de cada uma para que as correções apontem para o
+// trecho exato do documento
+func splitWordSpans(content string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	flush := func(end int) {
+		word := content[start:end]
+		trimmedLeft := strings.TrimLeft(word, ".,!?;:")
+		trimmed := strings.TrimRight(trimmedLeft, ".,!?;:")
+		if trimmed != "" {
+			spans = append(spans, wordSpan{
+				position: start + len(word) - len(trimmedLeft),
+				text:     trimmed,
+			})
+		}
+		start = -1
+	}
+	for i, r := range content {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				flush(i)
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		flush(len(content))
+	}
+	return spans
+}
+
+// calculateTextTransformationConfidence pontua uma correção pelo contexto e
+// pelo que os revisores já decidiram sobre strategy e sobre a mesma troca
+// ngramContextRadius bytes de cada lado da correção comparados pelo modelo de n-gramas
+const ngramContextRadius = 3
+
+func (e *Engine) calculateTextTransformationConfidence(content string, pos int, original, corrected, strategy string) float64 {
+	// Confiança baseada em contexto e frequência
+	baseConfidence := 0.8
+
+	// Verifica se a correção forma palavras válidas
+	if e.ngramModel != nil {
+		context := extractContext(content, pos, ngramContextRadius)
+		correctedContext := strings.Replace(context, original, corrected, 1)
+
+		// Calcula probabilidade dos n-gramas
+		originalProb := e.ngramModel.GetProbability(context)
+		correctedProb := e.ngramModel.GetProbability(correctedContext)
+
+		if correctedProb > originalProb {
+			baseConfidence += 0.1
+		}
+	}
+
+	return clampScore(baseConfidence + e.learnedAdjustment(strategy, content, pos, original, corrected))
+}
+
+// clampScore mantém a pontuação ajustada entre 0 e 1
+func clampScore(score float64) float64 {
+	return min(max(score, 0), 1)
+}
+
+func findSimilarWord(word string, cutoff float64) string {
+	// Implementação simples de busca por similaridade
+	// Em uma implementação real, usaria algoritmos como Levenshtein distance
+	commonWords := []string{"ação", "não", "são", "então", "coração", "informação"}
+
+	for _, candidate := range commonWords {
+		if calculateSimilarity(word, candidate) > cutoff {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func calculateSimilarity(a, b string) float64 {
+	// Implementação simples de similaridade
+	if a == b {
+		return 1.0
+	}
+
+	maxLen := len(a)
+	if len(b) > maxLen {
+		maxLen = len(b)
+	}
+
+	if maxLen == 0 {
+		return 1.0
+	}
+
+	// Conta caracteres em comum
+	common := 0
+	for i, r := range a {
+		if i < len(b) && rune(b[i]) == r {
+			common++
+		}
+	}
+
+	return float64(common) / float64(maxLen)
+}
+
+func calculateConfidence(anomalyCount int, corrections []TextTransformation) float64 {
+	if anomalyCount == 0 {
+		return 1.0
+	}
+
+	if len(corrections) == 0 {
+		return 0.0
+	}
+
+	// Calcula confiança média das correções
+	totalConfidence := 0.0
+	for _, correction := range corrections {
+		totalConfidence += correction.TransformationScore
+	}
+
+	avgConfidence := totalConfidence / float64(len(corrections))
+
+	// Ajusta baseado na proporção de problemas corrigidos
+	correctionRatio := float64(len(corrections)) / float64(anomalyCount)
+	if correctionRatio > 1.0 {
+		correctionRatio = 1.0
+	}
+
+	return avgConfidence * correctionRatio
+}
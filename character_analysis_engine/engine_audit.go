@@ -0,0 +1,13 @@
+package main
+
+import "demojibake/engine"
+
+// verifyAuditLogJSON confere os arquivos contra o log de auditoria
+// configurado em auditLog na configuração da instância
+func (h *engineHandle) verifyAuditLogJSON() string {
+	result, err := h.instance.VerifyAuditLog()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(result)
+}
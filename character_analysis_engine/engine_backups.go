@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"demojibake/engine"
+)
+
+// Repositório de backups do host, configurado em backups na configuração da
+// instância. Caminho vazio em ListBackups lista todos; timestamp vazio em
+// RestoreBackup restaura a versão mais recente.
+
+// defaultBackupDir repositório usado quando a configuração não define
+// backups.dir, o mesmo da CLI: backup_files nunca grava cópias .bak ao lado
+// dos documentos do host
+func defaultBackupDir() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "demojibake", "backups")
+}
+
+// listBackupsJSON lista os backups de um documento, ou todos
+func (h *engineHandle) listBackupsJSON(path string) string {
+	entries, err := h.instance.ListBackups(path)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(entries)
+}
+
+// restoreBackupJSON restaura um documento. O destino passa pela política de
+// caminhos; um documento removido é validado pelo diretório onde será recriado.
+func (h *engineHandle) restoreBackupJSON(path, timestamp string) string {
+	var pathErr *engine.Error
+	if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+		_, pathErr = h.instance.PathPolicy().CheckDirectory(filepath.Dir(path))
+	} else {
+		_, pathErr = h.instance.PathPolicy().CheckFile(path)
+	}
+	if pathErr != nil {
+		return marshalError(h.recordError(pathErr))
+	}
+
+	var at time.Time
+	if timestamp != "" {
+		parsed, err := engine.ParseBackupTime(timestamp)
+		if err != nil {
+			return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeInvalidArgument)))
+		}
+		at = parsed
+	}
+	entry, err := h.instance.RestoreBackup(path, at)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(entry)
+}
+
+// pruneBackupsJSON aplica a política de retenção configurada
+func (h *engineHandle) pruneBackupsJSON() string {
+	result, err := h.instance.PruneBackups()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(result)
+}
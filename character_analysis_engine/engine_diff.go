@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"demojibake/engine"
+)
+
+// Exportação das correções como diff unificado. diffOptions é um JSON com
+// context, git e root (ver engine.DiffOptions); vazio usa o padrão.
+
+// parseDiffOptions lê as opções de diff sobre os valores padrão
+func (h *engineHandle) parseDiffOptions(diffOptionsJSON string) (engine.DiffOptions, *engine.Error) {
+	diffOptions := engine.DefaultDiffOptions()
+	if diffOptionsJSON != "" {
+		if err := json.Unmarshal([]byte(diffOptionsJSON), &diffOptions); err != nil {
+			return diffOptions, h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, "invalid diff options", map[string]interface{}{"cause": err.Error()}))
+		}
+	}
+	if diffOptions.Context < 0 {
+		return diffOptions, h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, "context must not be negative", map[string]interface{}{"context": diffOptions.Context}))
+	}
+	return diffOptions, nil
+}
+
+// exportDocumentDiffJSON diff das correções sugeridas para um documento
+func (h *engineHandle) exportDocumentDiffJSON(path, optionsJSON, diffOptionsJSON string) string {
+	patch, err := h.exportPatch([]string{path}, optionsJSON, diffOptionsJSON)
+	if err != nil {
+		return marshalError(err)
+	}
+	return h.marshalResult(patch.Files[0])
+}
+
+// exportCollectionPatchJSON diffs de uma lista JSON de caminhos, concatenados em um patch
+func (h *engineHandle) exportCollectionPatchJSON(pathsJSON, optionsJSON, diffOptionsJSON string) string {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "paths must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
+	}
+	patch, err := h.exportPatch(paths, optionsJSON, diffOptionsJSON)
+	if err != nil {
+		return marshalError(err)
+	}
+	return h.marshalResult(patch)
+}
+
+// exportPatch valida caminhos e opções e gera os diffs; o erro já está registrado
+func (h *engineHandle) exportPatch(paths []string, optionsJSON, diffOptionsJSON string) (*engine.Patch, *engine.Error) {
+	resolvedPaths, pathErr := h.resolvePaths(paths)
+	if pathErr != nil {
+		return nil, pathErr
+	}
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return nil, h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil))
+	}
+	diffOptions, diffErr := h.parseDiffOptions(diffOptionsJSON)
+	if diffErr != nil {
+		return nil, diffErr
+	}
+
+	patch, err := h.instance.DiffFiles(context.Background(), resolvedPaths, options, diffOptions)
+	if err != nil {
+		return nil, h.recordError(engine.AsError(err, engine.ErrCodeIO))
+	}
+	return patch, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+
+	"demojibake/engine"
+)
+
+// Status numéricos devolvidos pelas funções exportadas que retornam int.
+// Valores negativos sempre indicam falha; o detalhe fica em GetLastError.
+// ProcessDocumentCollectionConcurrently devolve, quando positivo, quantos
+// arquivos do lote falharam.
+var engineErrorStatus = map[string]int{
+	engine.ErrCodeNotInitialized:  -1,
+	engine.ErrCodeInvalidArgument: -2,
+	engine.ErrCodeInvalidPath:     -3,
+	engine.ErrCodeInvalidOptions:  -4,
+	engine.ErrCodeIO:              -5,
+	engine.ErrCodeSerialization:   -6,
+	engine.ErrCodeInternal:        -7,
+	engine.ErrCodeInvalidHandle:   -8,
+	engine.ErrCodeNotRunning:      -9,
+	engine.ErrCodeAlreadyRunning:  -10,
+	engine.ErrCodeCancelled:       -11,
+	engine.ErrCodeCharset:         -12,
+	engine.ErrCodeLimitExceeded:   -13,
+	engine.ErrCodeStaleAnalysis:   -14,
+}
+
+// errorEnvelope formato único de erro serializado para o host
+type errorEnvelope struct {
+	Error *engine.Error `json:"error"`
+}
+
+// errorStatusCode retorna o status numérico negativo correspondente ao código
+func errorStatusCode(e *engine.Error) int {
+	if status, ok := engineErrorStatus[e.Code]; ok {
+		return status
+	}
+	return engineErrorStatus[engine.ErrCodeInternal]
+}
+
+// marshalError serializa o erro no envelope padrão. json.Marshal cuida do
+// escape de aspas e caracteres de controle presentes na mensagem.
+func marshalError(e *engine.Error) string {
+	data, err := json.Marshal(errorEnvelope{Error: e})
+	if err != nil {
+		return `{"error":{"code":"internal_error","message":"failed to serialize error"}}`
+	}
+	return string(data)
+}
+
+var (
+	// Último erro sem instância: motor não inicializado, falha ao criar a
+	// instância ou handle inválido. Os demais ficam em cada engineHandle.
+	lastEngineError     *engine.Error
+	lastEngineErrorLock sync.Mutex
+)
+
+// recordEngineError guarda um erro sem instância para GetLastError
+func recordEngineError(e *engine.Error) *engine.Error {
+	lastEngineErrorLock.Lock()
+	lastEngineError = e
+	lastEngineErrorLock.Unlock()
+	return e
+}
+
+// lastErrorJSON resposta de GetLastError: o último erro da instância padrão
+// ou, antes da primeira inicialização, o último erro sem instância. O erro é
+// por instância, não por thread; chamadas concorrentes devem usar o envelope
+// que cada uma devolve.
+func lastErrorJSON() string {
+	defaultEngineLock.RLock()
+	h := defaultEngine
+	defaultEngineLock.RUnlock()
+	if h != nil {
+		return h.lastErrorJSON()
+	}
+	lastEngineErrorLock.Lock()
+	defer lastEngineErrorLock.Unlock()
+	if lastEngineError == nil {
+		return `{"error":null}`
+	}
+	return marshalError(lastEngineError)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"demojibake/engine"
+)
+
+// Aprendizado com as decisões dos revisores. O arquivo vem de feedbackFile
+// na configuração da instância.
+
+// feedbackDecisions corpo de RecordTransformationFeedback
+type feedbackDecisions struct {
+	Accepted []string `json:"accepted"`
+	Rejected []string `json:"rejected"`
+}
+
+// recordFeedbackJSON registra aceites e rejeições das correções de um
+// relatório guardado por AnalyzeDocumentSummary
+func (h *engineHandle) recordFeedbackJSON(path string, resultID int64, decisionsJSON string) string {
+	var decisions feedbackDecisions
+	if err := json.Unmarshal([]byte(decisionsJSON), &decisions); err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "decisions must be a JSON object with accepted and rejected id lists", map[string]interface{}{"cause": err.Error()})))
+	}
+	report, lookupErr := h.results.get(resultID)
+	if lookupErr != nil {
+		return marshalError(h.recordError(lookupErr))
+	}
+	resolvedPaths, pathErr := h.resolvePaths([]string{path})
+	if pathErr != nil {
+		return marshalError(pathErr)
+	}
+
+	result, err := h.instance.RecordFeedback(context.Background(), resolvedPaths[0], report, decisions.Accepted, decisions.Rejected)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	result.Path = path
+	return h.marshalResult(result)
+}
+
+// learnedFeedbackJSON decisões acumuladas e ajustes atuais
+func (h *engineHandle) learnedFeedbackJSON() string {
+	summary, err := h.instance.LearnedFeedback()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(summary)
+}
+
+// resetFeedbackJSON esquece o aprendido para uma estratégia, ou tudo se vazia
+func (h *engineHandle) resetFeedbackJSON(strategy string) string {
+	if err := h.instance.ResetFeedback(strategy); err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	summary, err := h.instance.LearnedFeedback()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(summary)
+}
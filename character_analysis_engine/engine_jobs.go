@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"demojibake/engine"
+)
+
+// Jobs retomáveis do host. O diretório dos diários vem de jobDirectory na
+// configuração da instância; após uma queda o host lista os jobs em
+// andamento com ListCollectionJobs e os retoma ou desfaz pelo id.
+
+// jobResult resposta das exportações de job: a situação do job e as falhas
+// desta execução
+type jobResult struct {
+	engine.JobInfo
+	Errors []scanFileResult `json:"errors,omitempty"`
+}
+
+// startJobJSON cria e executa um job sobre uma lista JSON de caminhos
+func (h *engineHandle) startJobJSON(pathsJSON, optionsJSON string, apply bool) string {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "paths must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
+	}
+	resolvedPaths, pathErr := h.resolvePaths(paths)
+	if pathErr != nil {
+		return marshalError(pathErr)
+	}
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)))
+	}
+
+	job, err := engine.CreateJob(h.instance.JobDirectory(), resolvedPaths, options, apply)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	defer job.Close()
+	return h.runJobJSON(job)
+}
+
+// resumeJobJSON retoma um job interrompido
+func (h *engineHandle) resumeJobJSON(jobID string) string {
+	job, err := engine.OpenJob(h.instance.JobDirectory(), jobID)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	defer job.Close()
+	// Os caminhos do diário já passaram pela política na criação do job;
+	// arquivos removidos desde então aparecem como falhas individuais
+	return h.runJobJSON(job)
+}
+
+// runJobJSON executa o job e serializa o resultado
+func (h *engineHandle) runJobJSON(job *engine.Job) string {
+	result := jobResult{}
+	var errors []scanFileResult
+	var errorsLock sync.Mutex
+	info, err := h.instance.RunJob(context.Background(), job, func(path string, report *engine.Report, err error) {
+		if err != nil {
+			// visit é chamada pelos workers em paralelo
+			fileErr := h.recordError(engine.AsError(err, engine.ErrCodeIO))
+			errorsLock.Lock()
+			errors = append(errors, scanFileResult{Path: path, Error: fileErr})
+			errorsLock.Unlock()
+		}
+	})
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeInternal)))
+	}
+	result.JobInfo = info
+	result.Errors = errors
+	return h.marshalResult(result)
+}
+
+// rollbackJobJSON abandona um job desfazendo as correções interrompidas
+func (h *engineHandle) rollbackJobJSON(jobID string) string {
+	job, err := engine.OpenJob(h.instance.JobDirectory(), jobID)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	defer job.Close()
+	info, err := h.instance.RollbackJob(job)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(info)
+}
+
+// listJobsJSON lista os jobs do diretório configurado
+func (h *engineHandle) listJobsJSON() string {
+	jobs, err := h.instance.ListJobs()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(jobs)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"demojibake/engine"
+)
+
+// Configuração de projeto (.demojibake.json). A descoberta nas análises
+// depende de projectConfig na configuração da instância; GetEffectiveConfig
+// responde sempre, para o host mostrar o que valeria para o documento.
+
+// effectiveConfigJSON configuração resolvida para path, com as opções da
+// chamada por cima
+func (h *engineHandle) effectiveConfigJSON(path, optionsJSON string) string {
+	resolvedPaths, pathErr := h.resolvePaths([]string{path})
+	if pathErr != nil {
+		return marshalError(pathErr)
+	}
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)))
+	}
+
+	effective, err := h.instance.EffectiveConfig(resolvedPaths[0], options)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	effective.Path = path
+	return h.marshalResult(effective)
+}
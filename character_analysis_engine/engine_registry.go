@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"demojibake/engine"
+)
+
+// engineHandle instância do motor exposta ao host, com seu último erro.
+// GetLastError e EngineGetLastError leem lastError: o erro é por instância.
+type engineHandle struct {
+	handle   int64
+	instance *engine.Engine
+
+	lastError     *engine.Error
+	lastErrorLock sync.Mutex
+
+	// Relatórios disponíveis para QueryAnalysisResult
+	results resultStore
+}
+
+// newEngineHandle cria uma instância a partir da configuração JSON do host,
+// repassando o corpus embutido na biblioteca
+func newEngineHandle(configJSON string) (*engineHandle, *engine.Error) {
+	config, err := engine.ParseConfig(configJSON)
+	if err != nil {
+		return nil, engine.AsError(err, engine.ErrCodeInvalidArgument)
+	}
+	config.Corpus = embeddedLanguageCorpus
+	if config.Backups.Dir == "" {
+		config.Backups.Dir = defaultBackupDir()
+	}
+	instance, err := engine.New(config)
+	if err != nil {
+		return nil, engine.AsError(err, engine.ErrCodeInternal)
+	}
+	return &engineHandle{instance: instance}, nil
+}
+
+// recordError guarda o último erro da instância
+func (h *engineHandle) recordError(err *engine.Error) *engine.Error {
+	h.lastErrorLock.Lock()
+	h.lastError = err
+	h.lastErrorLock.Unlock()
+	return err
+}
+
+// lastErrorJSON retorna o último erro da instância no envelope padrão
+func (h *engineHandle) lastErrorJSON() string {
+	h.lastErrorLock.Lock()
+	defer h.lastErrorLock.Unlock()
+	if h.lastError == nil {
+		return `{"error":null}`
+	}
+	return marshalError(h.lastError)
+}
+
+var (
+	// Registro de instâncias indexadas pelo handle opaco entregue ao host
+	engineRegistry     = make(map[int64]*engineHandle)
+	engineRegistryLock sync.RWMutex
+	nextEngineHandle   atomic.Int64
+)
+
+// registerEngine associa um novo handle à instância
+func registerEngine(h *engineHandle) int64 {
+	h.handle = nextEngineHandle.Add(1)
+
+	engineRegistryLock.Lock()
+	engineRegistry[h.handle] = h
+	engineRegistryLock.Unlock()
+	return h.handle
+}
+
+// lookupEngine resolve um handle recebido da camada C
+func lookupEngine(handle int64) (*engineHandle, *engine.Error) {
+	engineRegistryLock.RLock()
+	h, ok := engineRegistry[handle]
+	engineRegistryLock.RUnlock()
+	if !ok {
+		return nil, errInvalidHandle(handle)
+	}
+	return h, nil
+}
+
+// unregisterEngine remove o handle do registro
+func unregisterEngine(handle int64) {
+	engineRegistryLock.Lock()
+	delete(engineRegistry, handle)
+	engineRegistryLock.Unlock()
+}
+
+func errInvalidHandle(handle int64) *engine.Error {
+	return engine.NewError(engine.ErrCodeInvalidHandle, fmt.Sprintf("unknown engine handle %d", handle), map[string]interface{}{"handle": handle})
+}
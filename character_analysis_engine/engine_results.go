@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"demojibake/engine"
+)
+
+// maxCachedResults relatórios mantidos por instância para consulta paginada.
+// Ao passar do limite o mais antigo é descartado; o host deve liberar os
+// que não usa mais com ReleaseAnalysisResult.
+const maxCachedResults = 32
+
+// resultStore relatórios completos guardados do lado Go, indexados pelo id
+// devolvido no resumo, para que o host busque as listas página a página
+type resultStore struct {
+	lock    sync.Mutex
+	next    int64
+	order   []int64
+	reports map[int64]*engine.Report
+}
+
+// put guarda o relatório e devolve seu id
+func (s *resultStore) put(report *engine.Report) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.reports == nil {
+		s.reports = make(map[int64]*engine.Report)
+	}
+	s.next++
+	s.reports[s.next] = report
+	s.order = append(s.order, s.next)
+	for len(s.order) > maxCachedResults {
+		delete(s.reports, s.order[0])
+		s.order = s.order[1:]
+	}
+	return s.next
+}
+
+func (s *resultStore) get(id int64) (*engine.Report, *engine.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	report, ok := s.reports[id]
+	if !ok {
+		return nil, errInvalidResult(id)
+	}
+	return report, nil
+}
+
+func (s *resultStore) release(id int64) *engine.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.reports[id]; !ok {
+		return errInvalidResult(id)
+	}
+	delete(s.reports, id)
+	for i, cached := range s.order {
+		if cached == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func errInvalidResult(id int64) *engine.Error {
+	return engine.NewError(engine.ErrCodeInvalidHandle, fmt.Sprintf("unknown or expired analysis result %d", id), map[string]interface{}{"resultId": id})
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"demojibake/engine"
+)
+
+// applySelectedJSON grava só as correções aceitas de um relatório guardado
+// por AnalyzeDocumentSummary. acceptedJSON é uma lista JSON de IDs.
+func (h *engineHandle) applySelectedJSON(path string, resultID int64, acceptedJSON string) string {
+	var accepted []string
+	if err := json.Unmarshal([]byte(acceptedJSON), &accepted); err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "accepted ids must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
+	}
+	report, lookupErr := h.results.get(resultID)
+	if lookupErr != nil {
+		return marshalError(h.recordError(lookupErr))
+	}
+	resolvedPaths, pathErr := h.resolvePaths([]string{path})
+	if pathErr != nil {
+		return marshalError(pathErr)
+	}
+
+	result, err := h.instance.ApplySelected(context.Background(), resolvedPaths[0], report, accepted)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	result.Path = path
+	return h.marshalResult(result)
+}
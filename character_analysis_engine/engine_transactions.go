@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"demojibake/engine"
+)
+
+// Transações de correção do host. O diretório vem de transactionDirectory na
+// configuração da instância; UndoTransaction restaura os originais guardados.
+
+// applyTransactionJSON corrige uma lista JSON de caminhos como uma unidade
+func (h *engineHandle) applyTransactionJSON(pathsJSON, optionsJSON string) string {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidArgument, "paths must be a JSON array of strings", map[string]interface{}{"cause": err.Error()})))
+	}
+	resolvedPaths, pathErr := h.resolvePaths(paths)
+	if pathErr != nil {
+		return marshalError(pathErr)
+	}
+	options, _, err := engine.ParseOptions(optionsJSON, h.instance.DefaultOptions())
+	if err != nil {
+		return marshalError(h.recordError(engine.NewError(engine.ErrCodeInvalidOptions, err.Error(), nil)))
+	}
+
+	tx, err := h.instance.ApplyTransaction(context.Background(), h.instance.TransactionDirectory(), resolvedPaths, options)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(tx)
+}
+
+// undoTransactionJSON restaura os originais de uma transação
+func (h *engineHandle) undoTransactionJSON(transactionID string) string {
+	tx, err := h.instance.UndoTransaction(transactionID)
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(tx)
+}
+
+// listTransactionsJSON lista as transações do diretório configurado
+func (h *engineHandle) listTransactionsJSON() string {
+	transactions, err := h.instance.ListTransactions()
+	if err != nil {
+		return marshalError(h.recordError(engine.AsError(err, engine.ErrCodeIO)))
+	}
+	return h.marshalResult(transactions)
+}
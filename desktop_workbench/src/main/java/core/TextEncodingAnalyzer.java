@@ -21,10 +21,66 @@ public interface TextEncodingAnalyzer extends Library {
     int InitializeEncodingEngine();
     String AnalyzeDocumentEncoding(String documentPath, String analysisOptions);
     int ProcessDocumentCollectionConcurrently(String documentPathsJson, DocumentAnalysisProgressCallback callback, String processingOptions);
+    String ScanDirectoryConcurrently(String rootDirectory, String scanOptions, String processingOptions);
+    String AnalyzeDocumentSummary(String documentPath, String analysisOptions);
+    String QueryAnalysisResult(long resultId, String queryJson);
+    int ReleaseAnalysisResult(long resultId);
+    String StartCollectionJob(String documentPathsJson, String processingOptions, int apply);
+    String ResumeCollectionJob(String jobId);
+    String RollbackCollectionJob(String jobId);
+    String ListCollectionJobs();
+    String ApplyTransaction(String documentPathsJson, String processingOptions);
+    String UndoTransaction(String transactionId);
+    String ListTransactions();
+    String ListBackups(String documentPath);
+    String RestoreBackup(String documentPath, String timestamp);
+    String PruneBackups();
+    String VerifyAuditLog();
+    String ExportDocumentDiff(String documentPath, String analysisOptions, String diffOptions);
+    String ExportCollectionPatch(String documentPathsJson, String processingOptions, String diffOptions);
+    String ApplySelectedTransformations(String documentPath, long analysisId, String acceptedIdsJson);
+    String RecordTransformationFeedback(String documentPath, long analysisId, String decisionsJson);
+    String GetLearnedFeedback();
+    String ResetLearnedFeedback(String strategy);
+    String GetEffectiveConfig(String documentPath, String analysisOptions);
     String RetrieveLanguageDictionaryMetrics();
     int EnrichLanguageDictionary(String vocabularyTerms);
+    String GetLastError();
     void ReleaseAllocatedMemory(Pointer memoryPtr);
     void GracefulEngineShutdown();
+    int ShutdownEncodingEngine(int timeoutMillis);
+    int InitializeEncodingEngineWithConfig(String engineConfigJson);
+    
+    // Handle-based engine instances (independent configuration and dictionary)
+    long CreateEngine(String engineConfigJson);
+    int DestroyEngine(long engineHandle);
+    String EngineAnalyzeDocumentEncoding(long engineHandle, String documentPath, String analysisOptions);
+    int EngineProcessDocumentCollectionConcurrently(long engineHandle, String documentPathsJson, String processingOptions);
+    String EngineScanDirectoryConcurrently(long engineHandle, String rootDirectory, String scanOptions, String processingOptions);
+    String EngineAnalyzeDocumentSummary(long engineHandle, String documentPath, String analysisOptions);
+    String EngineQueryAnalysisResult(long engineHandle, long resultId, String queryJson);
+    int EngineReleaseAnalysisResult(long engineHandle, long resultId);
+    String EngineStartCollectionJob(long engineHandle, String documentPathsJson, String processingOptions, int apply);
+    String EngineResumeCollectionJob(long engineHandle, String jobId);
+    String EngineRollbackCollectionJob(long engineHandle, String jobId);
+    String EngineListCollectionJobs(long engineHandle);
+    String EngineApplyTransaction(long engineHandle, String documentPathsJson, String processingOptions);
+    String EngineUndoTransaction(long engineHandle, String transactionId);
+    String EngineListTransactions(long engineHandle);
+    String EngineListBackups(long engineHandle, String documentPath);
+    String EngineRestoreBackup(long engineHandle, String documentPath, String timestamp);
+    String EnginePruneBackups(long engineHandle);
+    String EngineVerifyAuditLog(long engineHandle);
+    String EngineExportDocumentDiff(long engineHandle, String documentPath, String analysisOptions, String diffOptions);
+    String EngineExportCollectionPatch(long engineHandle, String documentPathsJson, String processingOptions, String diffOptions);
+    String EngineApplySelectedTransformations(long engineHandle, String documentPath, long analysisId, String acceptedIdsJson);
+    String EngineRecordTransformationFeedback(long engineHandle, String documentPath, long analysisId, String decisionsJson);
+    String EngineGetLearnedFeedback(long engineHandle);
+    String EngineResetLearnedFeedback(long engineHandle, String strategy);
+    String EngineGetEffectiveConfig(long engineHandle, String documentPath, String analysisOptions);
+    String EngineRetrieveLanguageDictionaryMetrics(long engineHandle);
+    int EngineEnrichLanguageDictionary(long engineHandle, String vocabularyTerms);
+    String EngineGetLastError(long engineHandle);
     
     // Callback interface for analysis progress reporting
     interface DocumentAnalysisProgressCallback extends Callback {